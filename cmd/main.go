@@ -19,6 +19,8 @@ import (
 	"your_project/internal/db"
 	"your_project/internal/initializer"
 	"your_project/internal/logger"
+	"your_project/internal/mailer"
+	"your_project/internal/service"
 	"your_project/migrations"
 )
 
@@ -53,16 +55,48 @@ func main() {
 		logger.SystemLog.Infow("Go-based migrations applied successfully.")
 	}
 
+	// Load the JWT signing keys shared by handlers and middleware
+	security, err := initializer.NewSecurityContainer(config)
+	if err != nil {
+		logger.SystemLog.Fatalw("Failed to initialize JWT signing key", "error", err)
+	}
+
+	// Outgoing email used by the password reset flow
+	mail, err := mailer.New(config.MailerDriver, config.MailerFileDir, config.MailFrom)
+	if err != nil {
+		logger.SystemLog.Fatalw("Failed to initialize mailer", "error", err)
+	}
+
+	// File storage for uploads such as avatars
+	blob, err := initializer.NewBlobStorage(context.Background(), config)
+	if err != nil {
+		logger.SystemLog.Fatalw("Failed to initialize file storage", "error", err)
+	}
+
 	// Initialize repositories, services, and handlers using the initializer pattern
 	repos := initializer.NewRepositoryContainer(dbConn)
-	services := initializer.NewServiceContainer(repos, dbConn)
-	handlers := initializer.NewHandlerContainer(services, dbConn, config)
+	services := initializer.NewServiceContainer(repos, security, mail, blob, dbConn, config)
+	handlers := initializer.NewHandlerContainer(services, security, dbConn, config)
+
+	// Grant the admin role to the configured user so the admin API is reachable
+	if config.BootstrapAdminEmail != "" {
+		if err := services.Role.BootstrapAdmin(context.Background(), config.BootstrapAdminEmail); err != nil {
+			logger.SystemLog.Errorw("Failed to bootstrap admin user", "email", config.BootstrapAdminEmail, "error", err)
+		}
+	}
+
+	// Permanently delete users once their restore period is over
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	if config.UserPurgeRetentionDays > 0 && config.UserPurgeIntervalMinutes > 0 {
+		go runUserPurge(purgeCtx, services.User, time.Duration(config.UserPurgeIntervalMinutes)*time.Minute)
+	}
 
 	// Set up Gin router
 	r := gin.Default()
 
 	// Setup routes and apply middleware
-	api.SetupRoutes(r, handlers, config)
+	api.SetupRoutes(r, handlers, security)
 
 	// Create the HTTP server
 	serverAddr := fmt.Sprintf(":%s", config.Port)
@@ -79,6 +113,29 @@ func main() {
 		}
 	}()
 
+	// Rotate the JWT signing key on SIGHUP without restarting the server.
+	// Replace the key file (or the secret in .env) and run: kill -HUP <pid>
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			// Re-read .env so edited values win over the ones loaded at startup
+			if err := godotenv.Overload(); err != nil {
+				logger.SystemLog.Warnw("Error reloading .env file, using environment variables", "error", err)
+			}
+			newConfig, err := configs.LoadConfig()
+			if err != nil {
+				logger.SystemLog.Errorw("Failed to reload configuration", "error", err)
+				continue
+			}
+			if err := security.RotateSigningKey(newConfig); err != nil {
+				logger.SystemLog.Errorw("JWT signing key rotation failed", "error", err)
+				continue
+			}
+			logger.SystemLog.Infow("JWT signing key reloaded", "kid", security.JWT.ActiveKeyID())
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shut down the server
 	quit := make(chan os.Signal, 1)
 	// kill (no param) default send syscall.SIGTERM
@@ -88,6 +145,7 @@ func main() {
 	<-quit
 
 	logger.SystemLog.Infow("Shutting down server...")
+	stopPurge()
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
@@ -101,4 +159,22 @@ func main() {
 	logger.SystemLog.Infow("Server exiting")
 }
 
+// runUserPurge purges deleted users on every tick until ctx is cancelled
+func runUserPurge(ctx context.Context, users service.UserService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := users.PurgeDeletedUsers(ctx); err != nil {
+			logger.SystemLog.Errorw("Failed to purge deleted users", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // Remove the runMigrations function that used golang-migrate/migrate
@@ -13,6 +13,93 @@ type Config struct {
 	AutoMigrate    bool   `mapstructure:"AUTO_MIGRATE"`
 	JWTSecret      string `mapstructure:"JWT_SECRET"`
 	JWTExpiryHours int    `mapstructure:"JWT_EXPIRY_HOURS"`
+
+	// Asymmetric signing (RS256, ES256, EdDSA); HS256 falls back to JWT_SECRET
+	JWTAlgorithm      string `mapstructure:"JWT_ALGORITHM"`
+	JWTPrivateKeyPath string `mapstructure:"JWT_PRIVATE_KEY_PATH"`
+	JWTKeyID          string `mapstructure:"JWT_KEY_ID"` // Overrides the derived kid of the active key
+
+	// Key rotation: the previous key keeps verifying tokens until JWT_PREVIOUS_KEY_RETIRES_AT (RFC 3339)
+	JWTPreviousAlgorithm      string `mapstructure:"JWT_PREVIOUS_ALGORITHM"`
+	JWTPreviousSecret         string `mapstructure:"JWT_PREVIOUS_SECRET"`
+	JWTPreviousPrivateKeyPath string `mapstructure:"JWT_PREVIOUS_PRIVATE_KEY_PATH"`
+	JWTPreviousKeyID          string `mapstructure:"JWT_PREVIOUS_KEY_ID"`
+	JWTPreviousKeyRetiresAt   string `mapstructure:"JWT_PREVIOUS_KEY_RETIRES_AT"`
+	// How long the replaced key keeps verifying after a runtime rotation (SIGHUP)
+	JWTRotationGraceHours int `mapstructure:"JWT_ROTATION_GRACE_HOURS"`
+
+	// Server-side secret used to hash refresh tokens before they are stored
+	TokenPepper string `mapstructure:"TOKEN_PEPPER"`
+
+	// Public URL serving the frontend and /api, used to build links in emails
+	AppBaseURL              string `mapstructure:"APP_BASE_URL"`
+	PasswordResetTTLMinutes int    `mapstructure:"PASSWORD_RESET_TTL_MINUTES"`
+
+	// Email verification
+	RequireVerifiedEmail              bool `mapstructure:"REQUIRE_VERIFIED_EMAIL"` // Refuse logins for unverified accounts
+	EmailVerificationTTLHours         int  `mapstructure:"EMAIL_VERIFICATION_TTL_HOURS"`
+	VerificationResendCooldownSeconds int  `mapstructure:"VERIFICATION_RESEND_COOLDOWN_SECONDS"`
+
+	// Deleted users can be restored for USER_PURGE_RETENTION_DAYS (0 keeps them forever);
+	// the purge job runs every USER_PURGE_INTERVAL_MINUTES
+	UserPurgeRetentionDays   int `mapstructure:"USER_PURGE_RETENTION_DAYS"`
+	UserPurgeIntervalMinutes int `mapstructure:"USER_PURGE_INTERVAL_MINUTES"`
+
+	// Email changes: lifetime of the confirmation link sent to the new address
+	EmailChangeTTLHours int `mapstructure:"EMAIL_CHANGE_TTL_HOURS"`
+
+	// Outgoing email: "log" writes to system.log, "file" writes .eml files to MAILER_FILE_DIR
+	MailerDriver  string `mapstructure:"MAILER_DRIVER"`
+	MailerFileDir string `mapstructure:"MAILER_FILE_DIR"`
+	MailFrom      string `mapstructure:"MAIL_FROM"`
+
+	// File storage: "local" keeps files in STORAGE_LOCAL_DIR, "s3" in an S3-compatible bucket (e.g. MinIO)
+	StorageDriver   string `mapstructure:"STORAGE_DRIVER"`
+	StorageLocalDir string `mapstructure:"STORAGE_LOCAL_DIR"`
+	S3Endpoint      string `mapstructure:"S3_ENDPOINT"`
+	S3Bucket        string `mapstructure:"S3_BUCKET"`
+	S3Region        string `mapstructure:"S3_REGION"`
+	S3AccessKey     string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey     string `mapstructure:"S3_SECRET_KEY"`
+	S3UseSSL        bool   `mapstructure:"S3_USE_SSL"`
+
+	// Largest accepted avatar upload
+	AvatarMaxBytes int64 `mapstructure:"AVATAR_MAX_BYTES"`
+
+	// Two-factor authentication: issuer name shown in authenticator apps
+	MFAIssuer string `mapstructure:"MFA_ISSUER"`
+	// Base64 encoded 32 byte keys encrypting TOTP secrets; the previous key only decrypts, during a rotation
+	MFAEncryptionKey         string `mapstructure:"MFA_ENCRYPTION_KEY"`
+	MFAPreviousEncryptionKey string `mapstructure:"MFA_PREVIOUS_ENCRYPTION_KEY"`
+
+	// Email of a user who is granted the admin role on startup
+	BootstrapAdminEmail string `mapstructure:"BOOTSTRAP_ADMIN_EMAIL"`
+
+	// Failed login lockout: thresholds per account and per client IP (0 disables),
+	// then a lockout that doubles from the base up to the maximum (0 for no maximum)
+	LoginMaxAccountFailures   int `mapstructure:"LOGIN_MAX_ACCOUNT_FAILURES"`
+	LoginMaxIPFailures        int `mapstructure:"LOGIN_MAX_IP_FAILURES"`
+	LoginFailureWindowMinutes int `mapstructure:"LOGIN_FAILURE_WINDOW_MINUTES"`
+	LoginLockoutBaseSeconds   int `mapstructure:"LOGIN_LOCKOUT_BASE_SECONDS"`
+	LoginLockoutMaxMinutes    int `mapstructure:"LOGIN_LOCKOUT_MAX_MINUTES"`
+
+	// Password hashing: "argon2id" (PHC string format) or "bcrypt". Hashes made with another
+	// algorithm or weaker parameters are upgraded on the next successful login.
+	PasswordHashAlgorithm string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	Argon2MemoryKiB       uint32 `mapstructure:"ARGON2_MEMORY_KIB"`
+	Argon2Iterations      uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism     uint8  `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost            int    `mapstructure:"BCRYPT_COST"`
+
+	// Password policy for new passwords. The breach check is off unless a directory with
+	// the HIBP password list (one <SHA-1 prefix>.txt file per prefix) is configured.
+	PasswordMinLength            int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUppercase     bool   `mapstructure:"PASSWORD_REQUIRE_UPPERCASE"`
+	PasswordRequireLowercase     bool   `mapstructure:"PASSWORD_REQUIRE_LOWERCASE"`
+	PasswordRequireDigit         bool   `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol        bool   `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordDisallowPersonalInfo bool   `mapstructure:"PASSWORD_DISALLOW_PERSONAL_INFO"`
+	PasswordBreachedCorpusDir    string `mapstructure:"PASSWORD_BREACHED_CORPUS_DIR"`
 }
 
 func LoadConfig() (config Config, err error) {
@@ -35,6 +122,57 @@ func LoadConfig() (config Config, err error) {
 	// Set default value for AUTO_MIGRATE if not provided
 	v.SetDefault("AUTO_MIGRATE", false)
 	v.SetDefault("JWT_EXPIRY_HOURS", 24) // Default to 24 hours
+	v.SetDefault("JWT_ALGORITHM", "HS256")
+	v.SetDefault("JWT_PRIVATE_KEY_PATH", "")
+	v.SetDefault("JWT_KEY_ID", "")
+	v.SetDefault("JWT_PREVIOUS_ALGORITHM", "")
+	v.SetDefault("JWT_PREVIOUS_SECRET", "")
+	v.SetDefault("JWT_PREVIOUS_PRIVATE_KEY_PATH", "")
+	v.SetDefault("JWT_PREVIOUS_KEY_ID", "")
+	v.SetDefault("JWT_PREVIOUS_KEY_RETIRES_AT", "")
+	v.SetDefault("JWT_ROTATION_GRACE_HOURS", 0) // 0 means the refresh token lifetime
+	v.SetDefault("TOKEN_PEPPER", "")
+	v.SetDefault("APP_BASE_URL", "http://localhost:8080")
+	v.SetDefault("PASSWORD_RESET_TTL_MINUTES", 30)
+	v.SetDefault("REQUIRE_VERIFIED_EMAIL", false)
+	v.SetDefault("EMAIL_VERIFICATION_TTL_HOURS", 48)
+	v.SetDefault("VERIFICATION_RESEND_COOLDOWN_SECONDS", 60)
+	v.SetDefault("EMAIL_CHANGE_TTL_HOURS", 24)
+	v.SetDefault("USER_PURGE_RETENTION_DAYS", 30)
+	v.SetDefault("USER_PURGE_INTERVAL_MINUTES", 60)
+	v.SetDefault("MAILER_DRIVER", "log")
+	v.SetDefault("MAILER_FILE_DIR", "mail")
+	v.SetDefault("MAIL_FROM", "no-reply@example.com")
+	v.SetDefault("STORAGE_DRIVER", "local")
+	v.SetDefault("STORAGE_LOCAL_DIR", "uploads")
+	v.SetDefault("S3_ENDPOINT", "")
+	v.SetDefault("S3_BUCKET", "")
+	v.SetDefault("S3_REGION", "")
+	v.SetDefault("S3_ACCESS_KEY", "")
+	v.SetDefault("S3_SECRET_KEY", "")
+	v.SetDefault("S3_USE_SSL", true)
+	v.SetDefault("AVATAR_MAX_BYTES", 5<<20)
+	v.SetDefault("MFA_ISSUER", "Go Boilerplate")
+	v.SetDefault("MFA_ENCRYPTION_KEY", "")
+	v.SetDefault("MFA_PREVIOUS_ENCRYPTION_KEY", "")
+	v.SetDefault("BOOTSTRAP_ADMIN_EMAIL", "")
+	v.SetDefault("LOGIN_MAX_ACCOUNT_FAILURES", 5)
+	v.SetDefault("LOGIN_MAX_IP_FAILURES", 20)
+	v.SetDefault("LOGIN_FAILURE_WINDOW_MINUTES", 15)
+	v.SetDefault("LOGIN_LOCKOUT_BASE_SECONDS", 60)
+	v.SetDefault("LOGIN_LOCKOUT_MAX_MINUTES", 60)
+	v.SetDefault("PASSWORD_HASH_ALGORITHM", "argon2id")
+	v.SetDefault("ARGON2_MEMORY_KIB", 65536)
+	v.SetDefault("ARGON2_ITERATIONS", 3)
+	v.SetDefault("ARGON2_PARALLELISM", 2)
+	v.SetDefault("BCRYPT_COST", 10)
+	v.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	v.SetDefault("PASSWORD_REQUIRE_UPPERCASE", false)
+	v.SetDefault("PASSWORD_REQUIRE_LOWERCASE", false)
+	v.SetDefault("PASSWORD_REQUIRE_DIGIT", false)
+	v.SetDefault("PASSWORD_REQUIRE_SYMBOL", false)
+	v.SetDefault("PASSWORD_DISALLOW_PERSONAL_INFO", true)
+	v.SetDefault("PASSWORD_BREACHED_CORPUS_DIR", "")
 
 	err = v.Unmarshal(&config)
 	return
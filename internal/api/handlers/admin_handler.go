@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"your_project/internal/logger"
+	"your_project/internal/pkg"
+	"your_project/internal/repository"
+	"your_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler serves the administration API. Routes are guarded by RequirePermission.
+type AdminHandler struct {
+	*BaseHandler
+	roles service.RoleService
+	users service.UserService
+	bulk  service.UserBulkService
+}
+
+func NewAdminHandler(roles service.RoleService, users service.UserService, bulk service.UserBulkService) *AdminHandler {
+	return &AdminHandler{
+		BaseHandler: NewBaseHandler(),
+		roles:       roles,
+		users:       users,
+		bulk:        bulk,
+	}
+}
+
+// ListRoles returns every role with its permissions
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roles.ListRoles(c.Request.Context())
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// GetUserRoles returns the roles assigned to a user
+func (h *AdminHandler) GetUserRoles(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid user ID"))
+		return
+	}
+
+	roles, err := h.roles.GetUserRoles(c.Request.Context(), uint(id))
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// AssignRole gives a user a role
+func (h *AdminHandler) AssignRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid user ID"))
+		return
+	}
+
+	if err := h.roles.AssignRole(c.Request.Context(), uint(id), c.Param("role")); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role assigned successfully"})
+}
+
+// RemoveRole takes a role away from a user
+func (h *AdminHandler) RemoveRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid user ID"))
+		return
+	}
+
+	if err := h.roles.RemoveRole(c.Request.Context(), uint(id), c.Param("role")); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role removed successfully"})
+}
+
+// UnlockUser lifts a login lockout on a user's account
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid user ID"))
+		return
+	}
+
+	if err := h.users.UnlockUser(c.Request.Context(), uint(id)); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked successfully"})
+}
+
+// ListDeletedUsers returns a page of soft-deleted users that can still be restored
+func (h *AdminHandler) ListDeletedUsers(c *gin.Context) {
+	page, err := pageRequestFromQuery(c)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	users, err := h.users.ListUsers(c.Request.Context(), repository.UserFilter{OnlyDeleted: true}, page)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pkg.MapPage(users, NewUserResponse))
+}
+
+// RestoreUser undoes the deletion of a user
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid user ID"))
+		return
+	}
+
+	user, err := h.users.RestoreUser(c.Request.Context(), uint(id))
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// maxImportSize limits the body of a bulk import
+const maxImportSize = 32 << 20
+
+// ImportUsers creates users from a CSV or NDJSON body and reports the outcome of every row.
+// The format is taken from ?format=csv|ndjson or the Content-Type; ?dry_run=true rolls back.
+func (h *AdminHandler) ImportUsers(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		var err error
+		if format, err = pkg.RecordFormatFromContentType(c.ContentType()); err != nil {
+			h.ErrorHandler.HandleError(c, err)
+			return
+		}
+	}
+	dryRun, err := boolQuery(c, "dry_run")
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+	batchSize, err := intQuery(c, "batch_size")
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	report, err := h.bulk.ImportUsers(c.Request.Context(), body, format, service.ImportOptions{DryRun: dryRun, BatchSize: batchSize})
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = pkg.NewPayloadTooLargeError(maxImportSize, c.Request.ContentLength, "Import must not exceed %d bytes", maxImportSize)
+		}
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportUsers streams the users matching the list filters as CSV (the default) or NDJSON
+func (h *AdminHandler) ExportUsers(c *gin.Context) {
+	format := c.DefaultQuery("format", pkg.RecordFormatCSV)
+	contentType, ok := pkg.RecordContentTypes[format]
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewValidationError("format", format, "Format must be csv or ndjson"))
+		return
+	}
+	filter, err := userFilterFromQuery(c)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, format))
+	if err := h.bulk.ExportUsers(c.Request.Context(), c.Writer, format, filter); err != nil {
+		if c.Writer.Written() {
+			// The status is already sent; the client sees a truncated file
+			logger.SystemLog.Errorw("User export failed", "error", err)
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		h.ErrorHandler.HandleError(c, err)
+	}
+}
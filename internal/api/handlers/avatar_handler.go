@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"your_project/internal/middleware"
+	"your_project/internal/pkg"
+	"your_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AvatarHandler serves user profile pictures
+type AvatarHandler struct {
+	*BaseHandler
+	svc      service.AvatarService
+	maxBytes int64
+}
+
+func NewAvatarHandler(svc service.AvatarService, maxBytes int64) *AvatarHandler {
+	return &AvatarHandler{
+		BaseHandler: NewBaseHandler(),
+		svc:         svc,
+		maxBytes:    maxBytes,
+	}
+}
+
+// UploadMe replaces the authenticated user's avatar with the image in the request body
+func (h *AvatarHandler) UploadMe(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	// Refuse oversized uploads before reading them when the client announces the size
+	if c.Request.ContentLength > h.maxBytes {
+		h.ErrorHandler.HandleError(c, pkg.NewPayloadTooLargeError(h.maxBytes, c.Request.ContentLength, "Avatar must not exceed %d bytes", h.maxBytes))
+		return
+	}
+
+	user, err := h.svc.UploadAvatar(c.Request.Context(), userID, c.Request.Body)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.Header("ETag", pkg.VersionETag(user.Version))
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// DeleteMe removes the authenticated user's avatar
+func (h *AvatarHandler) DeleteMe(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.svc.DeleteAvatar(c.Request.Context(), userID); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetAvatar sends a user's avatar, or its thumbnail with ?size=thumbnail
+func (h *AvatarHandler) GetAvatar(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid user ID"))
+		return
+	}
+	size := c.DefaultQuery("size", "full")
+	if size != "full" && size != "thumbnail" {
+		h.ErrorHandler.HandleError(c, pkg.NewValidationError("size", size, "Size must be full or thumbnail"))
+		return
+	}
+
+	file, object, err := h.svc.GetAvatar(c.Request.Context(), uint(id), size == "thumbnail")
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+	defer file.Close()
+
+	// Every upload gets a new key, so the key identifies the content
+	etag := strconv.Quote(object.Key)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, max-age=3600")
+	if pkg.IfNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, file, nil)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"your_project/internal/model"
+)
+
+// Request and response bodies of the user endpoints. GORM models are never bound from
+// or rendered to clients directly, so stored fields such as the password hash stay internal.
+
+// SignUpRequest is the body of the signup (and create user) endpoint
+type SignUpRequest struct {
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+	Phone    string `json:"phone" validate:"required"`
+}
+
+// ToModel maps the request to a new user; the password is hashed by the service
+func (r SignUpRequest) ToModel() *model.User {
+	return &model.User{
+		Name:     r.Name,
+		Email:    r.Email,
+		Password: r.Password,
+		Phone:    r.Phone,
+	}
+}
+
+// UpdateUserRequest is the body of the update user endpoint.
+// A different email starts an email change instead of being applied directly.
+type UpdateUserRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ToModel maps the request onto the user with the given ID and expected version (0 for any)
+func (r UpdateUserRequest) ToModel(id, version uint) *model.User {
+	user := &model.User{
+		Name:  r.Name,
+		Email: r.Email,
+	}
+	user.ID = id
+	user.Version = version
+	return user
+}
+
+// UpdateProfileRequest is the body of PATCH /api/users/me; omitted fields keep their value
+type UpdateProfileRequest struct {
+	Name  *string `json:"name" validate:"omitempty,min=1"`
+	Email *string `json:"email" validate:"omitempty,email"`
+}
+
+// ApplyTo copies the fields present in the request onto user
+func (r UpdateProfileRequest) ApplyTo(user *model.User) {
+	if r.Name != nil {
+		user.Name = *r.Name
+	}
+	if r.Email != nil {
+		user.Email = *r.Email
+	}
+}
+
+// DeleteAccountRequest is the body of DELETE /api/users/me
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// EraseAccountRequest is the body of POST /api/users/me/erase
+type EraseAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// UserResponse is the public representation of a user
+type UserResponse struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Phone           string     `json:"phone"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	MFAEnabledAt    *time.Time `json:"mfa_enabled_at"`
+	PendingEmail    string     `json:"pending_email,omitempty"` // Requested address waiting for confirmation
+	AvatarURL       string     `json:"avatar_url,omitempty"`    // Omitted without an avatar
+	ThumbnailURL    string     `json:"thumbnail_url,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"` // Only set in listings that include deleted users
+	Version         uint       `json:"version"`              // Also sent as the ETag of the user
+}
+
+// NewUserResponse maps a user to its public representation
+func NewUserResponse(user *model.User) UserResponse {
+	return UserResponse{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Phone:           user.Phone,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		MFAEnabledAt:    user.MFAEnabledAt,
+		PendingEmail:    user.PendingEmail,
+		AvatarURL:       avatarURL(user, false),
+		ThumbnailURL:    avatarURL(user, true),
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		DeletedAt:       deletedAt(user),
+		Version:         user.Version,
+	}
+}
+
+// SessionResponse is the public representation of a login session
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// NewSessionResponse maps a session to its public representation
+func NewSessionResponse(session model.Session) SessionResponse {
+	return SessionResponse{
+		ID:         session.ID,
+		DeviceName: session.DeviceName,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+}
+
+func avatarURL(user *model.User, thumbnail bool) string {
+	if user.AvatarKey == "" {
+		return ""
+	}
+	url := fmt.Sprintf("/api/users/%d/avatar", user.ID)
+	if thumbnail {
+		url += "?size=thumbnail"
+	}
+	return url
+}
+
+func deletedAt(user *model.User) *time.Time {
+	if !user.DeletedAt.Valid {
+		return nil
+	}
+	return &user.DeletedAt.Time
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"your_project/internal/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	*BaseHandler
+	jwtManager *pkg.JWTManager
+}
+
+func NewJWKSHandler(jwtManager *pkg.JWTManager) *JWKSHandler {
+	return &JWKSHandler{
+		BaseHandler: NewBaseHandler(),
+		jwtManager:  jwtManager,
+	}
+}
+
+func (h *JWKSHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/.well-known/jwks.json", h.GetJWKS)
+}
+
+// GetJWKS returns the public keys used to verify access tokens
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// Let verifiers cache the key set, but pick up new keys within a few minutes
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwtManager.JWKS())
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+
+	"your_project/internal/middleware"
+	"your_project/internal/pkg"
+	"your_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	*BaseHandler
+	svc service.MFAService
+}
+
+func NewMFAHandler(svc service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		BaseHandler: NewBaseHandler(),
+		svc:         svc,
+	}
+}
+
+// EnrollTOTP starts TOTP enrolment and returns the secret, otpauth URI and QR code
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var enrollData struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&enrollData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.svc.EnrollTOTP(c.Request.Context(), userID, enrollData.Password)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP enables two-factor authentication and returns the one-time recovery codes
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var confirmData struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&confirmData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := h.svc.ConfirmTOTP(c.Request.Context(), userID, confirmData.Code)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled, store the recovery codes somewhere safe",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTOTP turns two-factor authentication off
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var disableData struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"` // TOTP or recovery code
+	}
+	if err := c.ShouldBindJSON(&disableData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.DisableTOTP(c.Request.Context(), userID, disableData.Password, disableData.Code); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, invalidating the old ones
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var regenerateData struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&regenerateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), userID, regenerateData.Password, regenerateData.Code)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// VerifyLogin exchanges the mfa_pending token from Login and a second factor for a token pair
+func (h *MFAHandler) VerifyLogin(c *gin.Context) {
+	var verifyData struct {
+		MFAToken     string `json:"mfa_token" binding:"required"`
+		Code         string `json:"code" binding:"required_without=RecoveryCode"`
+		RecoveryCode string `json:"recovery_code"`
+		DeviceName   string `json:"device_name"`
+	}
+	if err := c.ShouldBindJSON(&verifyData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenPair, user, err := h.svc.VerifyLogin(c.Request.Context(), verifyData.MFAToken, verifyData.Code, verifyData.RecoveryCode, clientInfo(c, verifyData.DeviceName))
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Login successful",
+		"user":          NewUserResponse(user),
+		"access_token":  tokenPair.AccessToken,
+		"refresh_token": tokenPair.RefreshToken,
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"your_project/internal/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageRequestFromQuery reads the pagination parameters shared by every list endpoint:
+// limit, offset or cursor, sort (e.g. "-created_at,name") and total=false to skip the count
+func pageRequestFromQuery(c *gin.Context) (pkg.PageRequest, error) {
+	req := pkg.PageRequest{Cursor: c.Query("cursor"), WithTotal: true}
+
+	var err error
+	if req.Limit, err = intQuery(c, "limit"); err != nil {
+		return req, err
+	}
+	if req.Offset, err = intQuery(c, "offset"); err != nil {
+		return req, err
+	}
+	if req.Sort, err = pkg.ParseSort(c.Query("sort")); err != nil {
+		return req, err
+	}
+	if raw := c.Query("total"); raw != "" {
+		if req.WithTotal, err = strconv.ParseBool(raw); err != nil {
+			return req, pkg.NewValidationError("total", raw, "total must be true or false")
+		}
+	}
+	return req, nil
+}
+
+func intQuery(c *gin.Context, name string) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, pkg.NewValidationError(name, raw, "%s must be a number", name)
+	}
+	return value, nil
+}
+
+func timeQuery(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, pkg.NewValidationError(name, raw, "%s must be an RFC 3339 timestamp", name)
+	}
+	return &value, nil
+}
+
+func boolQuery(c *gin.Context, name string) (bool, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, pkg.NewValidationError(name, raw, "%s must be true or false", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"your_project/internal/middleware"
+	"your_project/internal/pkg"
+	"your_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// PrivacyHandler serves the data export and erasure endpoints
+type PrivacyHandler struct {
+	*BaseHandler
+	svc service.PrivacyService
+}
+
+func NewPrivacyHandler(svc service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		BaseHandler: NewBaseHandler(),
+		svc:         svc,
+	}
+}
+
+// ExportMe returns a download of the authenticated user's personal data.
+// ?format=zip (the default) sends an archive, ?format=json a single JSON document.
+func (h *PrivacyHandler) ExportMe(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "json" {
+		h.ErrorHandler.HandleError(c, pkg.NewValidationError("format", format, "Format must be zip or json"))
+		return
+	}
+
+	export, err := h.svc.ExportUserData(c.Request.Context(), userID, clientInfo(c, ""))
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("user_%d_export.%s", userID, format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "json" {
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	var archive bytes.Buffer
+	if err := export.WriteZip(&archive); err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInternalServerError(err, "failed to build export archive for user %d", userID))
+		return
+	}
+	c.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// EraseMe erases the authenticated user's personal data after confirming their password
+func (h *PrivacyHandler) EraseMe(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req EraseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate the request
+	if err := validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErrors.Error()})
+		return
+	}
+
+	if err := h.svc.EraseOwnAccount(c.Request.Context(), userID, req.Password); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// EraseUser erases the personal data of any user
+func (h *PrivacyHandler) EraseUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid user ID"))
+		return
+	}
+
+	if err := h.svc.EraseUser(c.Request.Context(), uint(id)); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
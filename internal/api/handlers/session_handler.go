@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"your_project/internal/middleware"
+	"your_project/internal/pkg"
+	"your_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	*BaseHandler
+	svc service.SessionService
+}
+
+func NewSessionHandler(svc service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		BaseHandler: NewBaseHandler(),
+		svc:         svc,
+	}
+}
+
+// ListSessions returns the authenticated user's active sessions
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	sessions, err := h.svc.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	response := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = NewSessionResponse(session)
+	}
+
+	currentSessionID, _ := middleware.GetSessionIDFromContext(c)
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":           response,
+		"current_session_id": currentSessionID,
+	})
+}
+
+// RevokeSession logs out one of the authenticated user's sessions
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("sessionID"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid session ID"))
+		return
+	}
+
+	if err := h.svc.RevokeSession(c.Request.Context(), userID, uint(sessionID)); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
@@ -1,13 +1,13 @@
 package handlers
 
 import (
+	"io"
 	"net/http"
 	"strconv"
-	"time"
 
-	"your_project/configs"
-	"your_project/internal/model"
+	"your_project/internal/middleware"
 	"your_project/internal/pkg"
+	"your_project/internal/repository"
 	"your_project/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -22,40 +22,42 @@ func init() {
 
 type UserHandler struct {
 	*BaseHandler
-	svc        service.UserService
-	jwtManager *pkg.JWTManager
+	svc  service.UserService
+	auth service.AuthService
+	mfa  service.MFAService
 }
 
-func NewUserHandler(svc service.UserService, config configs.Config) *UserHandler {
-	jwtManager := pkg.NewJWTManager(config.JWTSecret, config.JWTExpiryHours)
+func NewUserHandler(svc service.UserService, auth service.AuthService, mfa service.MFAService) *UserHandler {
 	return &UserHandler{
 		BaseHandler: NewBaseHandler(),
 		svc:         svc,
-		jwtManager:  jwtManager,
+		auth:        auth,
+		mfa:         mfa,
 	}
 }
 
 func (h *UserHandler) CreateUser(c *gin.Context) {
-	var user model.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var req SignUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Validate the user struct
-	if err := validate.Struct(user); err != nil {
+	// Validate the request
+	if err := validate.Struct(req); err != nil {
 		validationErrors := err.(validator.ValidationErrors)
 		c.JSON(http.StatusBadRequest, gin.H{"error": validationErrors.Error()})
 		return
 	}
 
+	user := req.ToModel()
 	// Pass the request context to the service layer
-	if err := h.svc.CreateUser(c.Request.Context(), &user); err != nil {
+	if err := h.svc.CreateUser(c.Request.Context(), user); err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, user)
+	c.JSON(http.StatusCreated, NewUserResponse(user))
 }
 
 func (h *UserHandler) GetUser(c *gin.Context) {
@@ -72,7 +74,38 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	etag := pkg.VersionETag(user.Version)
+	c.Header("ETag", etag)
+	if pkg.IfNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// ListUsers returns a page of users, filtered by the query parameters email_prefix, name,
+// created_after, created_before and include_deleted
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	page, err := pageRequestFromQuery(c)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	filter, err := userFilterFromQuery(c)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	users, err := h.svc.ListUsers(c.Request.Context(), filter, page)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pkg.MapPage(users, NewUserResponse))
 }
 
 func (h *UserHandler) UpdateUser(c *gin.Context) {
@@ -82,28 +115,72 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var user model.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Validate the user struct
-	if err := validate.Struct(user); err != nil {
+	// Validate the request
+	if err := validate.Struct(req); err != nil {
 		validationErrors := err.(validator.ValidationErrors)
 		c.JSON(http.StatusBadRequest, gin.H{"error": validationErrors.Error()})
 		return
 	}
 
-	user.ID = uint(id)
+	version, err := pkg.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
 
+	user := req.ToModel(uint(id), version)
 	// Pass the request context to the service layer
-	if err := h.svc.UpdateUser(c.Request.Context(), &user); err != nil {
+	if err := h.svc.UpdateUser(c.Request.Context(), user); err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.Header("ETag", pkg.VersionETag(user.Version))
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// maxPatchSize limits the body of PATCH requests
+const maxPatchSize = 64 << 10
+
+// PatchUser partially updates a user with a JSON merge patch (application/merge-patch+json)
+// or a JSON patch (application/json-patch+json)
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("invalid user ID"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchSize))
+	if err != nil {
+		h.ErrorHandler.HandleError(c, pkg.NewPayloadTooLargeError(maxPatchSize, c.Request.ContentLength, "Patch must not exceed %d bytes", maxPatchSize))
+		return
+	}
+	patch, err := pkg.NewPatch(c.ContentType(), body)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+	version, err := pkg.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	user, err := h.svc.PatchUser(c.Request.Context(), uint(id), version, patch)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.Header("ETag", pkg.VersionETag(user.Version))
+	c.JSON(http.StatusOK, NewUserResponse(user))
 }
 
 func (h *UserHandler) DeleteItem(c *gin.Context) {
@@ -113,8 +190,14 @@ func (h *UserHandler) DeleteItem(c *gin.Context) {
 		return
 	}
 
+	version, err := pkg.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
 	// Pass the request context to the service layer
-	if err := h.svc.DeleteUser(c.Request.Context(), uint(id)); err != nil {
+	if err := h.svc.DeleteUser(c.Request.Context(), uint(id), version); err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
 	}
@@ -122,47 +205,119 @@ func (h *UserHandler) DeleteItem(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
-// SignUp User
-func (h *UserHandler) SignUp(c *gin.Context) {
-	var user model.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+// GetMe returns the authenticated user
+func (h *UserHandler) GetMe(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	user, err := h.svc.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// UpdateMe changes the fields present in the body on the authenticated user.
+// A new email is only applied once it has been confirmed.
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Validate the user struct
-	if err := validate.Struct(user); err != nil {
+	// Validate the request
+	if err := validate.Struct(req); err != nil {
 		validationErrors := err.(validator.ValidationErrors)
 		c.JSON(http.StatusBadRequest, gin.H{"error": validationErrors.Error()})
 		return
 	}
 
-	// Pass the request context to the service layer
-	if err := h.svc.RegisterUser(c.Request.Context(), &user); err != nil {
+	user, err := h.svc.GetUser(c.Request.Context(), userID)
+	if err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
 	}
-	// Optionally, you can generate a token here or just return success
-	// For simplicity, we will just return a success message
-	// You can also generate a token pair here if needed
-	tokenPair, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email)
-	if err != nil {
-		h.ErrorHandler.HandleError(c, pkg.NewInternalServerError(err, "Failed to generate tokens"))
+	req.ApplyTo(user)
+
+	if err := h.svc.UpdateUser(c.Request.Context(), user); err != nil {
+		h.ErrorHandler.HandleError(c, err)
 		return
 	}
-	// Store refresh token in database
-	refreshExpiry := time.Now().Add(time.Duration(h.jwtManager.RefreshExpiryHours()) * time.Hour)
-	err = h.svc.UpdateRefreshToken(c.Request.Context(), user.ID, tokenPair.RefreshToken, refreshExpiry)
-	if err != nil {
+
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// DeleteMe deletes the authenticated user's account after confirming the password
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate the request
+	if err := validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErrors.Error()})
+		return
+	}
+
+	if err := h.svc.DeleteOwnAccount(c.Request.Context(), userID, req.Password); err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// SignUp User
+func (h *UserHandler) SignUp(c *gin.Context) {
+	var req SignUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate the request
+	if err := validate.Struct(req); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErrors.Error()})
+		return
+	}
+
+	user := req.ToModel()
+	// Pass the request context to the service layer
+	if err := h.svc.RegisterUser(c.Request.Context(), user); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+	// No session is started here; the user logs in to get tokens, which may first
+	// require verifying the email address
+	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully, check your email to verify your address"})
 }
 func (h *UserHandler) Login(c *gin.Context) {
 	var loginData struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
+		Email      string `json:"email" binding:"required,email"`
+		Password   string `json:"password" binding:"required"`
+		DeviceName string `json:"device_name"` // Optional, shown in the session list
 	}
 
 	if err := c.ShouldBindJSON(&loginData); err != nil {
@@ -170,22 +325,30 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.svc.LoginUser(c.Request.Context(), loginData.Email, loginData.Password)
+	user, err := h.svc.LoginUser(c.Request.Context(), loginData.Email, loginData.Password, c.ClientIP())
 	if err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
 	}
 
-	// Generate token pair
-	tokenPair, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email)
-	if err != nil {
-		h.ErrorHandler.HandleError(c, pkg.NewInternalServerError(err, "Failed to generate tokens"))
+	// With two-factor authentication the password alone only earns a short-lived
+	// token that has to be exchanged at /api/auth/mfa/verify
+	if user.MFAEnabledAt != nil {
+		mfaToken, err := h.mfa.IssuePendingToken(c.Request.Context(), user)
+		if err != nil {
+			h.ErrorHandler.HandleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
 		return
 	}
 
-	// Store refresh token in database
-	refreshExpiry := time.Now().Add(time.Duration(h.jwtManager.RefreshExpiryHours()) * time.Hour)
-	err = h.svc.UpdateRefreshToken(c.Request.Context(), user.ID, tokenPair.RefreshToken, refreshExpiry)
+	// Start a new session with its own refresh token family
+	tokenPair, err := h.auth.IssueTokens(c.Request.Context(), user, clientInfo(c, loginData.DeviceName))
 	if err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
@@ -193,7 +356,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Login successful",
-		"user":          user,
+		"user":          NewUserResponse(user),
 		"access_token":  tokenPair.AccessToken,
 		"refresh_token": tokenPair.RefreshToken,
 	})
@@ -210,44 +373,203 @@ func (h *UserHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Validate the refresh token
-	claims, err := h.jwtManager.ValidateToken(refreshData.RefreshToken)
+	// Exchange the refresh token; replaying a used token revokes its whole family
+	tokenPair, err := h.auth.RefreshTokens(c.Request.Context(), refreshData.RefreshToken)
 	if err != nil {
-		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("Invalid refresh token"))
+		h.ErrorHandler.HandleError(c, err)
 		return
 	}
 
-	// Check if it's actually a refresh token
-	if claims.TokenType != "refresh" {
-		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("Invalid token type - refresh token required"))
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Token refreshed successfully",
+		"access_token":  tokenPair.AccessToken,
+		"refresh_token": tokenPair.RefreshToken,
+	})
+}
+
+// Logout ends the current session and revokes the access token used for the request
+func (h *UserHandler) Logout(c *gin.Context) {
+	claims, ok := middleware.GetClaimsFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
 		return
 	}
 
-	// Get user by refresh token to ensure it exists in database
-	user, err := h.svc.RefreshToken(c.Request.Context(), refreshData.RefreshToken)
-	if err != nil {
+	if err := h.auth.Logout(c.Request.Context(), claims); err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
 	}
 
-	// Generate new token pair
-	tokenPair, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email)
-	if err != nil {
-		h.ErrorHandler.HandleError(c, pkg.NewInternalServerError(err, "Failed to generate new tokens"))
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll ends every session of the authenticated user
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.auth.LogoutAll(c.Request.Context(), userID); err != nil {
+		h.ErrorHandler.HandleError(c, err)
 		return
 	}
 
-	// Update refresh token in database
-	refreshExpiry := time.Now().Add(time.Duration(h.jwtManager.RefreshExpiryHours()) * time.Hour)
-	err = h.svc.UpdateRefreshToken(c.Request.Context(), user.ID, tokenPair.RefreshToken, refreshExpiry)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all sessions"})
+}
+
+// ForgotPassword emails a password reset link. The response is the same whether or not the email is registered.
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var forgotData struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&forgotData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.auth.RequestPasswordReset(c.Request.Context(), forgotData.Email); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+// ResetPassword sets a new password using the token from the reset email
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var resetData struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&resetData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.auth.ResetPassword(c.Request.Context(), resetData.Token, resetData.NewPassword); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in again"})
+}
+
+// ChangePassword sets a new password for the authenticated user. Other sessions are signed out
+// and the caller receives a fresh token pair for the current session.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	claims, ok := middleware.GetClaimsFromContext(c)
+	if !ok {
+		h.ErrorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var changeData struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&changeData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenPair, err := h.auth.ChangePassword(c.Request.Context(), claims, changeData.CurrentPassword, changeData.NewPassword, clientInfo(c, ""))
 	if err != nil {
 		h.ErrorHandler.HandleError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":       "Token refreshed successfully",
+		"message":       "Password changed, other sessions have been signed out",
 		"access_token":  tokenPair.AccessToken,
 		"refresh_token": tokenPair.RefreshToken,
 	})
 }
+
+// VerifyEmail confirms the user's email address using the token from the verification link
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("token query parameter is required"))
+		return
+	}
+
+	if err := h.auth.VerifyEmail(c.Request.Context(), token); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ConfirmEmailChange applies a pending email change using the token from the link sent to the new address
+func (h *UserHandler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		h.ErrorHandler.HandleError(c, pkg.NewInvalidInputError("token query parameter is required"))
+		return
+	}
+
+	if err := h.auth.ConfirmEmailChange(c.Request.Context(), token); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address changed, please sign in again with the new address"})
+}
+
+// ResendVerification sends a new verification link. The response does not reveal whether the email is registered.
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var resendData struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&resendData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.auth.ResendVerificationEmail(c.Request.Context(), resendData.Email); err != nil {
+		h.ErrorHandler.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered and unverified, a verification link has been sent"})
+}
+
+// userFilterFromQuery reads the user list filters: email_prefix, name, created_after,
+// created_before and include_deleted
+func userFilterFromQuery(c *gin.Context) (repository.UserFilter, error) {
+	filter := repository.UserFilter{
+		EmailPrefix:  c.Query("email_prefix"),
+		NameContains: c.Query("name"),
+	}
+
+	var err error
+	if filter.CreatedAfter, err = timeQuery(c, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = timeQuery(c, "created_before"); err != nil {
+		return filter, err
+	}
+	if filter.IncludeDeleted, err = boolQuery(c, "include_deleted"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// clientInfo describes the device making the request, for session tracking
+func clientInfo(c *gin.Context, deviceName string) service.ClientInfo {
+	userAgent := c.Request.UserAgent()
+	if deviceName == "" {
+		deviceName = userAgent
+	}
+	return service.ClientInfo{
+		DeviceName: deviceName,
+		UserAgent:  userAgent,
+		IPAddress:  c.ClientIP(),
+	}
+}
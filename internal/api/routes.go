@@ -1,19 +1,15 @@
 package api
 
 import (
-	"your_project/configs"
 	"your_project/internal/initializer"
 	"your_project/internal/middleware"
-	"your_project/internal/pkg"
+	"your_project/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes registers all API routes and applies middleware
-func SetupRoutes(r *gin.Engine, handlers *initializer.HandlerContainer, config configs.Config) {
-	// Initialize JWT manager for middleware
-	jwtManager := pkg.NewJWTManager(config.JWTSecret, config.JWTExpiryHours)
-
+func SetupRoutes(r *gin.Engine, handlers *initializer.HandlerContainer, security *initializer.SecurityContainer) {
 	// Apply global middleware
 	r.Use(middleware.RecoveryMiddleware())
 	r.Use(middleware.RequestIDMiddleware())
@@ -22,6 +18,9 @@ func SetupRoutes(r *gin.Engine, handlers *initializer.HandlerContainer, config c
 	// Register health check route (no middleware needed for health check)
 	handlers.Health.RegisterRoutes(r)
 
+	// Publish the token verification keys for other services
+	handlers.JWKS.RegisterRoutes(r)
+
 	// Group routes by functionality or version
 	apiRoutes := r.Group("/api")
 	{
@@ -31,15 +30,75 @@ func SetupRoutes(r *gin.Engine, handlers *initializer.HandlerContainer, config c
 			authRoutes.POST("/signup", handlers.User.SignUp)
 			authRoutes.POST("/login", handlers.User.Login)
 			authRoutes.POST("/refresh", handlers.User.RefreshToken)
+			authRoutes.POST("/forgot-password", handlers.User.ForgotPassword)
+			authRoutes.POST("/reset-password", handlers.User.ResetPassword)
+			authRoutes.GET("/verify-email", handlers.User.VerifyEmail)
+			authRoutes.GET("/confirm-email-change", handlers.User.ConfirmEmailChange)
+			authRoutes.POST("/resend-verification", handlers.User.ResendVerification)
+			authRoutes.POST("/mfa/verify", handlers.MFA.VerifyLogin)
+		}
+
+		// Authentication routes that need a valid access token
+		sessionAuthRoutes := apiRoutes.Group("/auth")
+		sessionAuthRoutes.Use(middleware.AuthMiddleware(security.JWT, security.Denylist))
+		{
+			sessionAuthRoutes.POST("/logout", handlers.User.Logout)
+			sessionAuthRoutes.POST("/logout-all", handlers.User.LogoutAll)
 		}
 
 		// Protected user routes
 		protectedUsers := apiRoutes.Group("/users")
-		protectedUsers.Use(middleware.AuthMiddleware(jwtManager))
+		protectedUsers.Use(middleware.AuthMiddleware(security.JWT, security.Denylist))
 		{
+			// Profile of the authenticated user
+			protectedUsers.GET("/me", handlers.User.GetMe)
+			protectedUsers.PATCH("/me", handlers.User.UpdateMe)
+			protectedUsers.DELETE("/me", handlers.User.DeleteMe)
+
+			// Sessions of the authenticated user
+			protectedUsers.GET("/me/sessions", handlers.Session.ListSessions)
+			protectedUsers.DELETE("/me/sessions/:sessionID", handlers.Session.RevokeSession)
+
+			// Password of the authenticated user
+			protectedUsers.POST("/me/password", handlers.User.ChangePassword)
+
+			// Two-factor authentication of the authenticated user
+			protectedUsers.POST("/me/mfa/totp", handlers.MFA.EnrollTOTP)
+			protectedUsers.POST("/me/mfa/totp/confirm", handlers.MFA.ConfirmTOTP)
+			protectedUsers.DELETE("/me/mfa/totp", handlers.MFA.DisableTOTP)
+			protectedUsers.POST("/me/mfa/recovery-codes", handlers.MFA.RegenerateRecoveryCodes)
+
+			// Avatar of the authenticated user
+			protectedUsers.PUT("/me/avatar", handlers.Avatar.UploadMe)
+			protectedUsers.DELETE("/me/avatar", handlers.Avatar.DeleteMe)
+
+			// Personal data of the authenticated user
+			protectedUsers.POST("/me/export", handlers.Privacy.ExportMe)
+			protectedUsers.POST("/me/erase", handlers.Privacy.EraseMe)
+
+			// Ownership or a users:* permission is enforced by the service policies
+			protectedUsers.GET("", handlers.User.ListUsers)
 			protectedUsers.GET("/:id", handlers.User.GetUser)
 			protectedUsers.PUT("/:id", handlers.User.UpdateUser)
+			protectedUsers.PATCH("/:id", handlers.User.PatchUser)
 			protectedUsers.DELETE("/:id", handlers.User.DeleteItem)
+			protectedUsers.GET("/:id/avatar", handlers.Avatar.GetAvatar)
+		}
+
+		// Administration routes, each guarded by a permission
+		adminRoutes := apiRoutes.Group("/admin")
+		adminRoutes.Use(middleware.AuthMiddleware(security.JWT, security.Denylist))
+		{
+			adminRoutes.GET("/roles", middleware.RequirePermission(model.PermissionRolesRead), handlers.Admin.ListRoles)
+			adminRoutes.GET("/users/:id/roles", middleware.RequirePermission(model.PermissionRolesRead), handlers.Admin.GetUserRoles)
+			adminRoutes.PUT("/users/:id/roles/:role", middleware.RequirePermission(model.PermissionRolesAssign), handlers.Admin.AssignRole)
+			adminRoutes.DELETE("/users/:id/roles/:role", middleware.RequirePermission(model.PermissionRolesAssign), handlers.Admin.RemoveRole)
+			adminRoutes.POST("/users/:id/unlock", middleware.RequirePermission(model.PermissionUsersUnlock), handlers.Admin.UnlockUser)
+			adminRoutes.POST("/users/import", middleware.RequirePermission(model.PermissionUsersImport), handlers.Admin.ImportUsers)
+			adminRoutes.GET("/users/export", middleware.RequirePermission(model.PermissionUsersRead), handlers.Admin.ExportUsers)
+			adminRoutes.GET("/users/deleted", middleware.RequirePermission(model.PermissionUsersReadDeleted), handlers.Admin.ListDeletedUsers)
+			adminRoutes.POST("/users/:id/restore", middleware.RequirePermission(model.PermissionUsersRestore), handlers.Admin.RestoreUser)
+			adminRoutes.POST("/users/:id/erase", middleware.RequirePermission(model.PermissionUsersErase), handlers.Privacy.EraseUser)
 		}
 
 		// Add other module routes here
@@ -2,48 +2,314 @@
 package initializer
 
 import (
+	"context"
+	"time"
+
 	"your_project/configs"
 	"your_project/internal/api/handlers"
+	"your_project/internal/mailer"
+	"your_project/internal/pkg"
 	"your_project/internal/repository"
 	"your_project/internal/service"
+	"your_project/internal/storage"
 
 	"gorm.io/gorm"
 )
 
+type SecurityContainer struct {
+	JWT         *pkg.JWTManager
+	Denylist    pkg.TokenDenylist
+	TokenHasher *pkg.TokenHasher
+	SecretBox   *pkg.SecretBox // Encrypts secrets stored in the database, e.g. TOTP seeds
+	// Failed login counters; kept for a day so repeated lockouts keep backing off
+	LoginAttempts pkg.LoginAttemptStore
+	// Hashes new passwords and verifies (and upgrades) existing hashes
+	PasswordHasher pkg.PasswordHasher
+	// Rules for new passwords, including the optional breached password check
+	PasswordPolicy *pkg.PasswordPolicy
+}
+
+func NewSecurityContainer(config configs.Config) (*SecurityContainer, error) {
+	signingKey, err := pkg.NewSigningKey(config.JWTAlgorithm, config.JWTSecret, config.JWTPrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if config.JWTKeyID != "" {
+		signingKey.ID = config.JWTKeyID
+	}
+	jwtManager := pkg.NewJWTManagerWithKey(signingKey, config.JWTExpiryHours)
+
+	// Keep verifying tokens signed by the previous key until it retires
+	if config.JWTPreviousSecret != "" || config.JWTPreviousPrivateKeyPath != "" {
+		algorithm := config.JWTPreviousAlgorithm
+		if algorithm == "" {
+			algorithm = config.JWTAlgorithm
+		}
+		previousKey, err := pkg.NewSigningKey(algorithm, config.JWTPreviousSecret, config.JWTPreviousPrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		if config.JWTPreviousKeyID != "" {
+			previousKey.ID = config.JWTPreviousKeyID
+		}
+
+		retiresAt, err := time.Parse(time.RFC3339, config.JWTPreviousKeyRetiresAt)
+		if err != nil {
+			return nil, pkg.NewConfigurationError("JWT_PREVIOUS_KEY_RETIRES_AT", "RFC 3339 timestamp", "invalid JWT_PREVIOUS_KEY_RETIRES_AT: %v", err)
+		}
+		if time.Now().Before(retiresAt) {
+			if err := jwtManager.AddRetiredKey(previousKey, retiresAt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tokenHasher, err := pkg.NewTokenHasher(config.TokenPepper)
+	if err != nil {
+		return nil, err
+	}
+
+	secretBox, err := newSecretBox(config)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHasher, err := pkg.NewPasswordHasher(config.PasswordHashAlgorithm, pkg.Argon2Params{
+		Memory:     config.Argon2MemoryKiB,
+		Iterations: config.Argon2Iterations,
+		Threads:    config.Argon2Parallelism,
+	}, config.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPolicy := &pkg.PasswordPolicy{
+		MinLength:            config.PasswordMinLength,
+		RequireUppercase:     config.PasswordRequireUppercase,
+		RequireLowercase:     config.PasswordRequireLowercase,
+		RequireDigit:         config.PasswordRequireDigit,
+		RequireSymbol:        config.PasswordRequireSymbol,
+		DisallowPersonalInfo: config.PasswordDisallowPersonalInfo,
+	}
+	if config.PasswordBreachedCorpusDir != "" {
+		corpus, err := pkg.NewHIBPCorpus(config.PasswordBreachedCorpusDir)
+		if err != nil {
+			return nil, err
+		}
+		passwordPolicy.Breached = corpus
+	}
+
+	return &SecurityContainer{
+		JWT:            jwtManager,
+		Denylist:       pkg.NewMemoryDenylist(time.Minute),
+		TokenHasher:    tokenHasher,
+		SecretBox:      secretBox,
+		LoginAttempts:  pkg.NewMemoryAttemptStore(time.Minute, 24*time.Hour),
+		PasswordHasher: passwordHasher,
+		PasswordPolicy: passwordPolicy,
+	}, nil
+}
+
+// newSecretBox builds the box encrypting TOTP seeds from MFA_ENCRYPTION_KEY. Seeds sealed with
+// MFA_PREVIOUS_ENCRYPTION_KEY can still be opened, and are sealed again with the current key on use.
+func newSecretBox(config configs.Config) (*pkg.SecretBox, error) {
+	if config.MFAEncryptionKey == "" {
+		return nil, pkg.NewConfigurationError("MFA_ENCRYPTION_KEY", "base64 encoded 32 byte key", "MFA_ENCRYPTION_KEY is required to encrypt TOTP secrets at rest")
+	}
+	key, err := pkg.ParseSecretBoxKey("MFA_ENCRYPTION_KEY", config.MFAEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous [][]byte
+	if config.MFAPreviousEncryptionKey != "" {
+		previousKey, err := pkg.ParseSecretBoxKey("MFA_PREVIOUS_ENCRYPTION_KEY", config.MFAPreviousEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, previousKey)
+	}
+	return pkg.NewSecretBox(key, previous...)
+}
+
+// RotateSigningKey loads the configured signing key and makes it the active key.
+// The replaced key keeps verifying for JWT_ROTATION_GRACE_HOURS, or the refresh
+// token lifetime when unset, so already issued tokens are not invalidated.
+func (s *SecurityContainer) RotateSigningKey(config configs.Config) error {
+	signingKey, err := pkg.NewSigningKey(config.JWTAlgorithm, config.JWTSecret, config.JWTPrivateKeyPath)
+	if err != nil {
+		return err
+	}
+	if config.JWTKeyID != "" {
+		signingKey.ID = config.JWTKeyID
+	}
+
+	graceHours := config.JWTRotationGraceHours
+	if graceHours <= 0 {
+		graceHours = s.JWT.RefreshExpiryHours()
+	}
+	return s.JWT.Rotate(signingKey, time.Duration(graceHours)*time.Hour)
+}
+
 type RepositoryContainer struct {
-	User repository.UserRepository
+	User              repository.UserRepository
+	RefreshToken      repository.RefreshTokenRepository
+	Session           repository.SessionRepository
+	PasswordReset     repository.PasswordResetTokenRepository
+	EmailVerification repository.EmailVerificationTokenRepository
+	EmailChange       repository.EmailChangeRequestRepository
+	RecoveryCode      repository.RecoveryCodeRepository
+	Role              repository.RoleRepository
+	AuditLog          repository.AuditLogRepository
 	// Add other repositories here
 }
 
+// NewBlobStorage connects the file storage selected by STORAGE_DRIVER
+func NewBlobStorage(ctx context.Context, config configs.Config) (storage.Blob, error) {
+	return storage.New(ctx, storage.Config{
+		Driver:      config.StorageDriver,
+		LocalDir:    config.StorageLocalDir,
+		S3Endpoint:  config.S3Endpoint,
+		S3Bucket:    config.S3Bucket,
+		S3Region:    config.S3Region,
+		S3AccessKey: config.S3AccessKey,
+		S3SecretKey: config.S3SecretKey,
+		S3UseSSL:    config.S3UseSSL,
+	})
+}
+
 func NewRepositoryContainer(db *gorm.DB) *RepositoryContainer {
 	return &RepositoryContainer{
-		User: repository.NewUserRepository(db),
+		User:              repository.NewUserRepository(db),
+		RefreshToken:      repository.NewRefreshTokenRepository(db),
+		Session:           repository.NewSessionRepository(db),
+		PasswordReset:     repository.NewPasswordResetTokenRepository(db),
+		EmailVerification: repository.NewEmailVerificationTokenRepository(db),
+		EmailChange:       repository.NewEmailChangeRequestRepository(db),
+		RecoveryCode:      repository.NewRecoveryCodeRepository(db),
+		Role:              repository.NewRoleRepository(db),
+		AuditLog:          repository.NewAuditLogRepository(db),
 		// Add other repositories here
 	}
 }
 
 type ServiceContainer struct {
-	User service.UserService
+	User    service.UserService
+	Auth    service.AuthService
+	Session service.SessionService
+	MFA     service.MFAService
+	Role    service.RoleService
+	Privacy service.PrivacyService
+	Bulk    service.UserBulkService
+	Avatar  service.AvatarService
 	// Add other services here
 }
 
-func NewServiceContainer(repos *RepositoryContainer, db *gorm.DB) *ServiceContainer {
+func NewServiceContainer(repos *RepositoryContainer, security *SecurityContainer, mail mailer.Mailer, blob storage.Blob, db *gorm.DB, config configs.Config) *ServiceContainer {
+	authSettings := service.AuthSettings{
+		AppBaseURL:                 config.AppBaseURL,
+		PasswordResetTTL:           time.Duration(config.PasswordResetTTLMinutes) * time.Minute,
+		EmailVerificationTTL:       time.Duration(config.EmailVerificationTTLHours) * time.Hour,
+		VerificationResendCooldown: time.Duration(config.VerificationResendCooldownSeconds) * time.Second,
+		EmailChangeTTL:             time.Duration(config.EmailChangeTTLHours) * time.Hour,
+	}
+	mfaSettings := service.MFASettings{
+		Issuer: config.MFAIssuer,
+	}
+	lockoutSettings := service.LockoutSettings{
+		MaxAccountFailures: config.LoginMaxAccountFailures,
+		MaxIPFailures:      config.LoginMaxIPFailures,
+		FailureWindow:      time.Duration(config.LoginFailureWindowMinutes) * time.Minute,
+		BaseLockout:        time.Duration(config.LoginLockoutBaseSeconds) * time.Second,
+		MaxLockout:         time.Duration(config.LoginLockoutMaxMinutes) * time.Minute,
+	}
+	userSettings := service.UserSettings{
+		RequireVerifiedEmail: config.RequireVerifiedEmail,
+		DeletedUserRetention: time.Duration(config.UserPurgeRetentionDays) * 24 * time.Hour,
+	}
+	avatarSettings := service.AvatarSettings{
+		MaxBytes: config.AvatarMaxBytes,
+	}
+
+	throttle := service.NewLoginThrottle(security.LoginAttempts, lockoutSettings)
+
+	auth := service.NewAuthService(
+		repos.User,
+		repos.RefreshToken,
+		repos.Session,
+		repos.PasswordReset,
+		repos.EmailVerification,
+		repos.EmailChange,
+		repos.Role,
+		repos.AuditLog,
+		security.JWT,
+		security.Denylist,
+		security.TokenHasher,
+		throttle,
+		security.PasswordHasher,
+		security.PasswordPolicy,
+		mail,
+		authSettings,
+		db,
+	)
+
 	return &ServiceContainer{
-		User: service.NewUserService(repos.User, db),
+		User:    service.NewUserService(repos.User, repos.Role, auth, throttle, security.PasswordHasher, security.PasswordPolicy, blob, userSettings, db),
+		Auth:    auth,
+		Session: service.NewSessionService(repos.Session, repos.RefreshToken, auth, db),
+		MFA: service.NewMFAService(
+			repos.User,
+			repos.RecoveryCode,
+			auth,
+			throttle,
+			security.JWT,
+			security.Denylist,
+			security.TokenHasher,
+			security.PasswordHasher,
+			security.SecretBox,
+			mfaSettings,
+			db,
+		),
+		Role: service.NewRoleService(repos.Role, repos.User, auth, db),
+		Bulk: service.NewUserBulkService(repos.User, security.PasswordHasher, security.PasswordPolicy, db),
+		Privacy: service.NewPrivacyService(
+			repos.User,
+			repos.Session,
+			repos.Role,
+			repos.AuditLog,
+			auth,
+			throttle,
+			security.PasswordHasher,
+			blob,
+			db,
+		),
+		Avatar: service.NewAvatarService(repos.User, blob, avatarSettings),
 		// Add other services here
 	}
 }
 
 type HandlerContainer struct {
-	User   *handlers.UserHandler
-	Health *handlers.HealthHandler
+	User    *handlers.UserHandler
+	Session *handlers.SessionHandler
+	Health  *handlers.HealthHandler
+	JWKS    *handlers.JWKSHandler
+	MFA     *handlers.MFAHandler
+	Admin   *handlers.AdminHandler
+	Privacy *handlers.PrivacyHandler
+	Avatar  *handlers.AvatarHandler
 	// Add other handlers here
 }
 
-func NewHandlerContainer(svcs *ServiceContainer, db *gorm.DB, config configs.Config) *HandlerContainer {
+func NewHandlerContainer(svcs *ServiceContainer, security *SecurityContainer, db *gorm.DB, config configs.Config) *HandlerContainer {
 	return &HandlerContainer{
-		User:   handlers.NewUserHandler(svcs.User, config),
-		Health: handlers.NewHealthHandler(db),
+		User:    handlers.NewUserHandler(svcs.User, svcs.Auth, svcs.MFA),
+		Avatar:  handlers.NewAvatarHandler(svcs.Avatar, config.AvatarMaxBytes),
+		Session: handlers.NewSessionHandler(svcs.Session),
+		Health:  handlers.NewHealthHandler(db),
+		JWKS:    handlers.NewJWKSHandler(security.JWT),
+		MFA:     handlers.NewMFAHandler(svcs.MFA),
+		Admin:   handlers.NewAdminHandler(svcs.Role, svcs.User, svcs.Bulk),
+		Privacy: handlers.NewPrivacyHandler(svcs.Privacy),
 		// Add other handlers here
 	}
 }
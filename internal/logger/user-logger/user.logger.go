@@ -1,6 +1,7 @@
 package userlogger
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -10,8 +11,15 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+const logDir = "logs"
+
+type userLogger struct {
+	sugar *zap.SugaredLogger
+	file  *os.File
+}
+
 var (
-	userLoggers = make(map[uint]*zap.SugaredLogger)
+	userLoggers = make(map[uint]*userLogger)
 	mu          sync.Mutex
 )
 
@@ -20,18 +28,15 @@ func GetUserLogger(userID uint) *zap.SugaredLogger {
 	defer mu.Unlock()
 
 	if logger, exists := userLoggers[userID]; exists {
-		return logger
+		return logger.sugar
 	}
 
-	logDir := "logs"
 	if err := os.MkdirAll(logDir, os.ModePerm); err != nil {
 		zap.L().Sugar().Warnf("Failed to create logs directory: %v", err)
 		return zap.L().Sugar()
 	}
 
-	// Convert uint to string for file name
-	userIDStr := strconv.FormatUint(uint64(userID), 10)
-	path := filepath.Join(logDir, "user_"+userIDStr+".log")
+	path := LogPath(userID)
 
 	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -46,6 +51,46 @@ func GetUserLogger(userID uint) *zap.SugaredLogger {
 	)
 	logger := zap.New(core, zap.AddCaller())
 	sugar := logger.Sugar()
-	userLoggers[userID] = sugar
+	userLoggers[userID] = &userLogger{sugar: sugar, file: file}
 	return sugar
 }
+
+// LogPath returns the path of the user's log file
+func LogPath(userID uint) string {
+	// Convert uint to string for file name
+	userIDStr := strconv.FormatUint(uint64(userID), 10)
+	return filepath.Join(logDir, "user_"+userIDStr+".log")
+}
+
+// ReadUserLog returns the content of the user's log file, or nil if there is none
+func ReadUserLog(userID uint) ([]byte, error) {
+	mu.Lock()
+	if logger, exists := userLoggers[userID]; exists {
+		_ = logger.sugar.Sync()
+	}
+	mu.Unlock()
+
+	data, err := os.ReadFile(LogPath(userID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// RemoveUserLog closes the user's logger and deletes their log file.
+// A later GetUserLogger call starts a new, empty file.
+func RemoveUserLog(userID uint) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if logger, exists := userLoggers[userID]; exists {
+		_ = logger.sugar.Sync()
+		_ = logger.file.Close()
+		delete(userLoggers, userID)
+	}
+
+	if err := os.Remove(LogPath(userID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
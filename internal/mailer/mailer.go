@@ -0,0 +1,88 @@
+// internal/mailer/mailer.go
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"your_project/internal/logger"
+
+	"github.com/google/uuid"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails. Implement it for a real provider (SMTP, SES, ...) in production.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Supported mailer drivers
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+)
+
+// New creates the mailer for the configured driver
+func New(driver, fileDir, from string) (Mailer, error) {
+	switch driver {
+	case "", DriverLog:
+		return &LogMailer{from: from}, nil
+	case DriverFile:
+		return &FileMailer{dir: fileDir, from: from}, nil
+	default:
+		return nil, fmt.Errorf("unsupported mailer driver: %s", driver)
+	}
+}
+
+// LogMailer writes emails to the system log instead of sending them. For local development only.
+type LogMailer struct {
+	from string
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	logger.SystemLog.Infow("Email (log mailer)",
+		"from", m.from,
+		"to", msg.To,
+		"subject", msg.Subject,
+		"body", msg.Body,
+	)
+	return nil
+}
+
+// FileMailer writes each email to its own .eml file, so local tools can open them. For local development only.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create mail directory %s: %w", m.dir, err)
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s_%s.eml", now.Format("20060102T150405"), uuid.New().String())
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), []byte(b.String()), 0o640); err != nil {
+		return fmt.Errorf("failed to write email to %s: %w", m.dir, err)
+	}
+	return nil
+}
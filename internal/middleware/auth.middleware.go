@@ -6,12 +6,13 @@ import (
 	"strings"
 
 	"your_project/internal/pkg"
+	"your_project/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AuthMiddleware creates a middleware for JWT authentication
-func AuthMiddleware(jwtManager *pkg.JWTManager) gin.HandlerFunc {
+func AuthMiddleware(jwtManager *pkg.JWTManager, denylist pkg.TokenDenylist) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -48,7 +49,7 @@ func AuthMiddleware(jwtManager *pkg.JWTManager) gin.HandlerFunc {
 		}
 
 		// Ensure this is an access token, not a refresh token
-		if claims.TokenType != "access" {
+		if claims.TokenType != pkg.TokenTypeAccess {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Invalid token type",
 				"message": "Access token required",
@@ -57,9 +58,33 @@ func AuthMiddleware(jwtManager *pkg.JWTManager) gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens revoked by logout, session revocation, logout-all or account deletion
+		revoked, err := denylist.IsRevoked(c.Request.Context(), claims)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Unable to verify token",
+				"message": "Token revocation check failed",
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Invalid token",
+				"message": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
 		// Store user information in context for later use
+		c.Set("claims", claims)
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("session_id", claims.SessionID)
+
+		// Services authorize against the principal carried by the request context
+		c.Request = c.Request.WithContext(service.WithPrincipal(c.Request.Context(), service.PrincipalFromClaims(claims)))
 
 		c.Next()
 	}
@@ -84,3 +109,23 @@ func GetUserEmailFromContext(c *gin.Context) (string, bool) {
 	email, ok := userEmail.(string)
 	return email, ok
 }
+
+// GetSessionIDFromContext extracts the login session ID from Gin context
+func GetSessionIDFromContext(c *gin.Context) (uint, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := sessionID.(uint)
+	return id, ok
+}
+
+// GetClaimsFromContext extracts the validated access token claims from Gin context
+func GetClaimsFromContext(c *gin.Context) (*pkg.JWTClaims, bool) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	jwtClaims, ok := claims.(*pkg.JWTClaims)
+	return jwtClaims, ok
+}
@@ -0,0 +1,36 @@
+// internal/middleware/permission.middleware.go
+package middleware
+
+import (
+	"strings"
+
+	"your_project/internal/logger"
+	"your_project/internal/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission only lets requests through whose access token carries the permission,
+// e.g. RequirePermission("users:delete"). It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	errorHandler := pkg.NewHTTPErrorHandler(logger.APILog)
+	resource, action, _ := strings.Cut(permission, ":")
+
+	return func(c *gin.Context) {
+		claims, ok := GetClaimsFromContext(c)
+		if !ok {
+			errorHandler.HandleError(c, pkg.NewUnauthorizedError("User not authenticated"))
+			c.Abort()
+			return
+		}
+
+		if !claims.HasPermission(permission) {
+			logger.APILog.Infow("Permission denied", "user_id", claims.UserID, "permission", permission, "path", c.FullPath())
+			errorHandler.HandleError(c, pkg.NewForbiddenError(resource, action, "Missing permission %s", permission))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package model
+
+import "gorm.io/gorm"
+
+// Audit log actions
+const (
+	AuditActionPasswordChanged      = "password.changed"
+	AuditActionEmailChangeRequested = "email.change_requested"
+	AuditActionEmailChanged         = "email.changed"
+	AuditActionDataExported         = "data.exported"
+	AuditActionUserErased           = "user.erased" // Tombstone left when a user's personal data is erased
+)
+
+// AuditLog records a security relevant change to an account
+type AuditLog struct {
+	gorm.Model
+	UserID    uint   `json:"user_id" gorm:"index;not null"` // The account that was changed
+	ActorID   uint   `json:"actor_id"`                      // Who made the change; equals UserID for self-service
+	Action    string `json:"action" gorm:"size:64;index;not null"`
+	IPAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+	Details   string `json:"details"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailChangeRequest is a pending email change, applied once the new address is confirmed
+type EmailChangeRequest struct {
+	gorm.Model
+	UserID      uint       `json:"user_id" gorm:"index;not null"`
+	NewEmail    string     `json:"new_email" gorm:"not null"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex;size:64;not null"` // HMAC-SHA256 of the token emailed to NewEmail
+	RequestedBy uint       `json:"requested_by"`                          // Actor who asked for the change
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+}
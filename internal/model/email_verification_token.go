@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailVerificationToken is a single-use token emailed to prove ownership of an address
+type EmailVerificationToken struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64;not null"` // HMAC-SHA256 of the emailed token
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken is a single-use token emailed to a user who forgot their password
+type PasswordResetToken struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64;not null"` // HMAC-SHA256 of the emailed token
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
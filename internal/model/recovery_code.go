@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the authenticator is lost
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash string     `json:"-" gorm:"size:64;not null"` // HMAC-SHA256 of the code
+	UsedAt   *time.Time `json:"used_at"`
+}
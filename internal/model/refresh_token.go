@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken is one issued refresh token. Every login starts a new family;
+// each refresh marks the presented token as used and issues the next one in the same family.
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	FamilyID  string     `json:"family_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64;not null"` // HMAC-SHA256 of the token, never the token itself
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`    // Set once the token has been exchanged
+	RevokedAt *time.Time `json:"revoked_at"` // Set when the whole family is revoked
+}
@@ -0,0 +1,37 @@
+package model
+
+import "gorm.io/gorm"
+
+// Built-in roles
+const (
+	RoleAdmin = "admin"
+)
+
+// Permissions checked by the API. Seeded by migrations.AutoMigrate.
+const (
+	PermissionUsersRead        = "users:read"
+	PermissionUsersReadDeleted = "users:read_deleted"
+	PermissionUsersImport      = "users:import"
+	PermissionUsersUpdate      = "users:update"
+	PermissionUsersDelete      = "users:delete"
+	PermissionUsersRestore     = "users:restore"
+	PermissionUsersUnlock      = "users:unlock"
+	PermissionUsersErase       = "users:erase"
+	PermissionRolesRead        = "roles:read"
+	PermissionRolesAssign      = "roles:assign"
+)
+
+// Role groups permissions and is assigned to users
+type Role struct {
+	gorm.Model
+	Name        string       `json:"name" gorm:"uniqueIndex;size:64;not null"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
+}
+
+// Permission is a single action on a resource, written as "resource:action"
+type Permission struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"uniqueIndex;size:64;not null"`
+	Description string `json:"description"`
+}
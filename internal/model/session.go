@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is one logged-in device. It is backed by a refresh token family,
+// so revoking the session revokes every refresh token issued to that device.
+type Session struct {
+	gorm.Model
+	UserID     uint       `json:"user_id" gorm:"index;not null"`
+	FamilyID   string     `json:"-" gorm:"uniqueIndex;not null"`
+	DeviceName string     `json:"device_name"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
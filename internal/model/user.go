@@ -9,10 +9,36 @@ import (
 
 type User struct {
 	gorm.Model
-	Name         string     `json:"name" validate:"required"`
-	Email        string     `json:"email" gorm:"uniqueIndex" validate:"required,email"`
-	Password     string     `json:"password" validate:"required,min=6"`
-	Phone        string     `json:"phone" validate:"required"`
-	RefreshToken string     `json:"-" gorm:"index"` // Store refresh token, exclude from JSON
-	TokenExpiry  *time.Time `json:"-"`              // Track when refresh token expires
+	Versioned
+	Name            string     `json:"name"`
+	Email           string     `json:"email" gorm:"uniqueIndex:idx_users_email_lower_active,expression:lower(email),where:deleted_at IS NULL"` // Unique regardless of case; deleted users do not block the address
+	Password        string     `json:"-"`                                                                                                      // Password hash; never rendered
+	Phone           string     `json:"phone"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // Nil until the user follows the verification link
+
+	// TOTP two-factor authentication
+	MFAEnabledAt     *time.Time `json:"mfa_enabled_at"` // Nil until an authenticator has been confirmed
+	TOTPSecret       string     `json:"-"`              // Encrypted TOTP seed, set on enrolment
+	TOTPLastUsedStep int64      `json:"-"`              // Last accepted time step, so a code cannot be replayed
+
+	// Profile picture: storage keys of the re-encoded image and its thumbnail; empty without one
+	AvatarKey          string `json:"-"`
+	AvatarThumbnailKey string `json:"-"`
+
+	// Set in responses while an email change waits for confirmation; not stored on the user
+	PendingEmail string `json:"pending_email,omitempty" gorm:"-"`
+
+	// Never bound from request bodies; managed through the admin API
+	Roles []Role `json:"-" gorm:"many2many:user_roles;"`
+}
+
+// AvatarKeys returns the storage keys of the user's avatar files
+func (u *User) AvatarKeys() []string {
+	var keys []string
+	for _, key := range []string{u.AvatarKey, u.AvatarThumbnailKey} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
 }
@@ -0,0 +1,7 @@
+package model
+
+// Versioned adds an optimistic locking version to a model. Repositories only save
+// a row while its version is unchanged and increment it with every update.
+type Versioned struct {
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
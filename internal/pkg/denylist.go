@@ -0,0 +1,145 @@
+// internal/pkg/denylist.go
+package pkg
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenDenylist records revoked access tokens until they would have expired anyway.
+// Implementations must be safe for concurrent use. The in-memory implementation only
+// covers a single instance; plug in a shared backend (e.g. Redis) when running several.
+type TokenDenylist interface {
+	// RevokeToken denies a single token by its jti until expiresAt
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// RevokeUserTokens denies every token of the user issued before issuedBefore, remembered until the given time
+	RevokeUserTokens(ctx context.Context, userID uint, issuedBefore, until time.Time) error
+	// RevokeSessionTokens denies every token carrying the session ID in its "sid" claim until the given time
+	RevokeSessionTokens(ctx context.Context, sessionID uint, until time.Time) error
+	// IsRevoked reports whether the token has been revoked
+	IsRevoked(ctx context.Context, claims *JWTClaims) (bool, error)
+}
+
+// userRevocation denies a user's tokens issued before a cutoff
+type userRevocation struct {
+	issuedBefore time.Time
+	until        time.Time
+}
+
+// MemoryDenylist is an in-memory TokenDenylist with TTL eviction
+type MemoryDenylist struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // jti -> expiry
+	users    map[uint]userRevocation
+	sessions map[uint]time.Time // sid -> expiry
+	stop     chan struct{}
+}
+
+// NewMemoryDenylist creates an in-memory denylist that evicts expired entries every cleanupInterval
+func NewMemoryDenylist(cleanupInterval time.Duration) *MemoryDenylist {
+	d := &MemoryDenylist{
+		tokens:   make(map[string]time.Time),
+		users:    make(map[uint]userRevocation),
+		sessions: make(map[uint]time.Time),
+		stop:     make(chan struct{}),
+	}
+	go d.evictLoop(cleanupInterval)
+	return d
+}
+
+func (d *MemoryDenylist) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (d *MemoryDenylist) RevokeUserTokens(ctx context.Context, userID uint, issuedBefore, until time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, ok := d.users[userID]; ok && existing.issuedBefore.After(issuedBefore) {
+		issuedBefore = existing.issuedBefore
+	}
+	d.users[userID] = userRevocation{issuedBefore: issuedBefore, until: until}
+	return nil
+}
+
+func (d *MemoryDenylist) RevokeSessionTokens(ctx context.Context, sessionID uint, until time.Time) error {
+	if sessionID == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, ok := d.sessions[sessionID]; ok && existing.After(until) {
+		until = existing
+	}
+	d.sessions[sessionID] = until
+	return nil
+}
+
+func (d *MemoryDenylist) IsRevoked(ctx context.Context, claims *JWTClaims) (bool, error) {
+	now := time.Now()
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if expiresAt, ok := d.tokens[claims.ID]; ok && now.Before(expiresAt) {
+		return true, nil
+	}
+	if until, ok := d.sessions[claims.SessionID]; ok && claims.SessionID != 0 && now.Before(until) {
+		return true, nil
+	}
+	if revocation, ok := d.users[claims.UserID]; ok && now.Before(revocation.until) {
+		if claims.IssuedBefore(revocation.issuedBefore) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Close stops the eviction goroutine
+func (d *MemoryDenylist) Close() {
+	close(d.stop)
+}
+
+func (d *MemoryDenylist) evictLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.evictExpired()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+func (d *MemoryDenylist) evictExpired() {
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for tokenID, expiresAt := range d.tokens {
+		if !now.Before(expiresAt) {
+			delete(d.tokens, tokenID)
+		}
+	}
+	for userID, revocation := range d.users {
+		if !now.Before(revocation.until) {
+			delete(d.users, userID)
+		}
+	}
+	for sessionID, until := range d.sessions {
+		if !now.Before(until) {
+			delete(d.sessions, sessionID)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testClaims(userID, sessionID uint, tokenID string, issuedAt time.Time) *JWTClaims {
+	return &JWTClaims{
+		UserID:        userID,
+		SessionID:     sessionID,
+		IssuedAtNanos: issuedAt.UnixNano(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       tokenID,
+			IssuedAt: jwt.NewNumericDate(issuedAt),
+		},
+	}
+}
+
+func assertRevoked(t *testing.T, denylist TokenDenylist, claims *JWTClaims, want bool) {
+	t.Helper()
+	revoked, err := denylist.IsRevoked(context.Background(), claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != want {
+		t.Errorf("IsRevoked(user %d, session %d, jti %q, iat %v) = %t, want %t",
+			claims.UserID, claims.SessionID, claims.ID, claims.IssuedAt.Time, revoked, want)
+	}
+}
+
+func TestMemoryDenylistRevokeToken(t *testing.T) {
+	denylist := NewMemoryDenylist(time.Hour)
+	defer denylist.Close()
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := denylist.RevokeToken(ctx, "revoked", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := denylist.RevokeToken(ctx, "expired", now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRevoked(t, denylist, testClaims(1, 0, "revoked", now), true)
+	assertRevoked(t, denylist, testClaims(1, 0, "expired", now), false)
+	assertRevoked(t, denylist, testClaims(1, 0, "other", now), false)
+}
+
+func TestMemoryDenylistRevokeUserTokens(t *testing.T) {
+	denylist := NewMemoryDenylist(time.Hour)
+	defer denylist.Close()
+	cutoff := time.Now()
+
+	if err := denylist.RevokeUserTokens(context.Background(), 1, cutoff, cutoff.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRevoked(t, denylist, testClaims(1, 0, "a", cutoff.Add(-time.Nanosecond)), true)
+	assertRevoked(t, denylist, testClaims(1, 0, "b", cutoff.Add(-time.Minute)), true)
+	assertRevoked(t, denylist, testClaims(2, 0, "c", cutoff.Add(-time.Minute)), false)
+	// Tokens issued right after the cutoff, e.g. on the next login, stay valid
+	assertRevoked(t, denylist, testClaims(1, 0, "d", cutoff), false)
+	assertRevoked(t, denylist, testClaims(1, 0, "e", cutoff.Add(time.Nanosecond)), false)
+}
+
+func TestMemoryDenylistTokensWithoutNanoseconds(t *testing.T) {
+	denylist := NewMemoryDenylist(time.Hour)
+	defer denylist.Close()
+	cutoff := time.Now()
+
+	if err := denylist.RevokeUserTokens(context.Background(), 1, cutoff, cutoff.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the second is known, so a token from the cutoff's second is denied
+	sameSecond := testClaims(1, 0, "a", cutoff)
+	sameSecond.IssuedAtNanos = 0
+	assertRevoked(t, denylist, sameSecond, true)
+
+	nextSecond := testClaims(1, 0, "b", cutoff.Truncate(time.Second).Add(time.Second))
+	nextSecond.IssuedAtNanos = 0
+	assertRevoked(t, denylist, nextSecond, false)
+}
+
+func TestMemoryDenylistKeepsLaterUserCutoff(t *testing.T) {
+	denylist := NewMemoryDenylist(time.Hour)
+	defer denylist.Close()
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := denylist.RevokeUserTokens(ctx, 1, now, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := denylist.RevokeUserTokens(ctx, 1, now.Add(-time.Hour), now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	assertRevoked(t, denylist, testClaims(1, 0, "a", now.Add(-time.Minute)), true)
+}
+
+func TestMemoryDenylistRevokeSessionTokens(t *testing.T) {
+	denylist := NewMemoryDenylist(time.Hour)
+	defer denylist.Close()
+	now := time.Now()
+
+	if err := denylist.RevokeSessionTokens(context.Background(), 5, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every token of the session is denied, whenever it was issued
+	assertRevoked(t, denylist, testClaims(1, 5, "a", now.Add(-time.Hour)), true)
+	assertRevoked(t, denylist, testClaims(1, 5, "b", now.Add(time.Minute)), true)
+	assertRevoked(t, denylist, testClaims(1, 6, "c", now), false)
+	assertRevoked(t, denylist, testClaims(1, 0, "d", now), false)
+}
+
+func TestMemoryDenylistEvictsExpiredEntries(t *testing.T) {
+	denylist := NewMemoryDenylist(time.Hour)
+	defer denylist.Close()
+	ctx := context.Background()
+	past := time.Now().Add(-time.Second)
+
+	_ = denylist.RevokeToken(ctx, "a", past)
+	_ = denylist.RevokeUserTokens(ctx, 1, past, past)
+	_ = denylist.RevokeSessionTokens(ctx, 5, past)
+	denylist.evictExpired()
+
+	if len(denylist.tokens) != 0 || len(denylist.users) != 0 || len(denylist.sessions) != 0 {
+		t.Errorf("expired entries kept: %d tokens, %d users, %d sessions", len(denylist.tokens), len(denylist.users), len(denylist.sessions))
+	}
+}
+
+func TestIssuedTokensCarryNanoseconds(t *testing.T) {
+	manager := NewJWTManager("secret", 1)
+	before := time.Now()
+	token, err := manager.GenerateToken(1, "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := manager.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.IssuedBefore(before) || !claims.IssuedBefore(time.Now().Add(time.Nanosecond)) {
+		t.Errorf("iat_ns %d is not between %d and now", claims.IssuedAtNanos, before.UnixNano())
+	}
+}
@@ -70,9 +70,16 @@ func NewDuplicateError(field, format string, a ...interface{}) error {
 
 // ValidationError represents validation errors with field-specific details
 type ValidationError struct {
-	Message string
-	Field   string
-	Value   interface{}
+	Message    string
+	Field      string
+	Value      interface{}
+	Violations []Violation // Every rule the value failed, when several rules are checked
+}
+
+// Violation is a single failed validation rule
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 func (e *ValidationError) Error() string {
@@ -87,6 +94,16 @@ func NewValidationError(field string, value interface{}, format string, a ...int
 	}
 }
 
+// NewRuleValidationError creates a validation error listing every failed rule for a field
+func NewRuleValidationError(field string, value interface{}, violations []Violation, format string, a ...interface{}) error {
+	return &ValidationError{
+		Message:    fmt.Sprintf(format, a...),
+		Field:      field,
+		Value:      value,
+		Violations: violations,
+	}
+}
+
 // UnauthorizedError represents authentication failures
 type UnauthorizedError struct {
 	Message string
@@ -136,6 +153,23 @@ func NewConflictError(details, format string, a ...interface{}) error {
 	}
 }
 
+// PreconditionFailedError represents a failed conditional request, e.g. a stale If-Match
+type PreconditionFailedError struct {
+	Message     string
+	CurrentETag string // ETag of the current representation, if known
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Message
+}
+
+func NewPreconditionFailedError(currentETag, format string, a ...interface{}) error {
+	return &PreconditionFailedError{
+		Message:     fmt.Sprintf(format, a...),
+		CurrentETag: currentETag,
+	}
+}
+
 // RateLimitError represents rate limiting errors
 type RateLimitError struct {
 	Message   string
@@ -495,6 +529,7 @@ func (h *HTTPErrorHandler) HandleError(c *gin.Context, err error) {
 	var unauthorizedErr *UnauthorizedError
 	var forbiddenErr *ForbiddenError
 	var conflictErr *ConflictError
+	var preconditionFailedErr *PreconditionFailedError
 	var rateLimitErr *RateLimitError
 	var serviceUnavailableErr *ServiceUnavailableError
 	var timeoutErr *TimeoutError
@@ -533,12 +568,16 @@ func (h *HTTPErrorHandler) HandleError(c *gin.Context, err error) {
 		})
 
 	case errors.As(err, &validationErr):
-		c.JSON(http.StatusBadRequest, gin.H{
+		response := gin.H{
 			"error": validationErr.Error(),
 			"type":  "validation",
 			"field": validationErr.Field,
 			"value": validationErr.Value,
-		})
+		}
+		if len(validationErr.Violations) > 0 {
+			response["violations"] = validationErr.Violations
+		}
+		c.JSON(http.StatusBadRequest, response)
 
 	case errors.As(err, &unauthorizedErr):
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -561,6 +600,16 @@ func (h *HTTPErrorHandler) HandleError(c *gin.Context, err error) {
 			"details": conflictErr.Details,
 		})
 
+	case errors.As(err, &preconditionFailedErr):
+		if preconditionFailedErr.CurrentETag != "" {
+			c.Header("ETag", preconditionFailedErr.CurrentETag)
+		}
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"error": preconditionFailedErr.Error(),
+			"type":  "precondition_failed",
+			"etag":  preconditionFailedErr.CurrentETag,
+		})
+
 	case errors.As(err, &rateLimitErr):
 		c.Header("Retry-After", fmt.Sprintf("%d", rateLimitErr.RetryTime))
 		c.JSON(http.StatusTooManyRequests, gin.H{
@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// VersionETag returns the strong ETag of a resource version
+func VersionETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ParseIfMatch returns the version an If-Match header requires. 0 means the header
+// is absent or "*", so any version matches. Weak ETags never match (RFC 9110).
+func ParseIfMatch(header string) (uint, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, NewPreconditionFailedError("", "If-Match requires a strong ETag")
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, NewInvalidInputError("If-Match must be a single ETag")
+	}
+	version, err := strconv.ParseUint(header[1:len(header)-1], 10, 64)
+	if err != nil || version == 0 {
+		return 0, NewPreconditionFailedError("", "If-Match does not match any version of this resource")
+	}
+	return uint(version), nil
+}
+
+// IfNoneMatch reports whether an If-None-Match header matches etag, using the weak comparison
+func IfNoneMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
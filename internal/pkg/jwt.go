@@ -3,30 +3,103 @@ package pkg
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // JWTClaims represents the JWT claims
 type JWTClaims struct {
 	UserID    uint   `json:"user_id"`
 	Email     string `json:"email"`
-	TokenType string `json:"token_type"` // "access" or "refresh"
+	TokenType string `json:"token_type"` // "access", "refresh" or "mfa_pending"
+	SessionID uint   `json:"sid,omitempty"`
+	// IssuedAtNanos is the issue time in nanoseconds; "iat" only has second precision
+	IssuedAtNanos int64 `json:"iat_ns,omitempty"`
+
+	// Authorization data, refreshed whenever a new token pair is issued
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// JWTManager handles JWT operations
+// IssuedBefore reports whether the token was issued before t. Tokens without "iat_ns"
+// only tell the second they were issued in, so one from the same second as t counts as before.
+func (c *JWTClaims) IssuedBefore(t time.Time) bool {
+	if c.IssuedAtNanos != 0 {
+		return time.Unix(0, c.IssuedAtNanos).Before(t)
+	}
+	return c.IssuedAt == nil || !c.IssuedAt.Time.After(t.Truncate(time.Second))
+}
+
+// TokenSubject identifies who a token is issued to and what they may do
+type TokenSubject struct {
+	UserID      uint
+	Email       string
+	Roles       []string
+	Permissions []string
+}
+
+// HasRole reports whether the token carries the given role
+func (c *JWTClaims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the token carries the given permission
+func (c *JWTClaims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Token types carried in the "token_type" claim
+const (
+	TokenTypeAccess     = "access"
+	TokenTypeRefresh    = "refresh"
+	TokenTypeMFAPending = "mfa_pending"
+)
+
+// MFAPendingTokenExpiry is how long a user has to enter their second factor after the password
+const MFAPendingTokenExpiry = 5 * time.Minute
+
+// JWTManager handles JWT operations.
+// It holds a key ring: the active key signs new tokens, retired keys keep
+// verifying tokens they signed until their retirement date passes.
 type JWTManager struct {
-	secretKey          string
+	mu                 sync.RWMutex
+	activeKey          *SigningKey
+	retiredKeys        map[string]retiredKey // Keyed by kid
 	expiryHours        int
 	refreshExpiryHours int
 }
 
-// NewJWTManager creates a new JWT manager
+// retiredKey is a key that no longer signs but still verifies until retiresAt
+type retiredKey struct {
+	key       *SigningKey
+	retiresAt time.Time
+}
+
+// NewJWTManager creates a new JWT manager that signs with HS256 and a shared secret
 func NewJWTManager(secretKey string, expiryHours int) *JWTManager {
+	return NewJWTManagerWithKey(NewHMACSigningKey(secretKey), expiryHours)
+}
+
+// NewJWTManagerWithKey creates a new JWT manager that signs with the given key
+func NewJWTManagerWithKey(key *SigningKey, expiryHours int) *JWTManager {
 	return &JWTManager{
-		secretKey:          secretKey,
+		activeKey:          key,
+		retiredKeys:        make(map[string]retiredKey),
 		expiryHours:        expiryHours,
 		refreshExpiryHours: expiryHours * 7, // Refresh tokens last 7 times longer
 	}
@@ -40,12 +113,19 @@ type TokenPair struct {
 
 // GenerateTokenPair generates both access and refresh tokens for a user
 func (j *JWTManager) GenerateTokenPair(userID uint, email string) (*TokenPair, error) {
-	accessToken, err := j.generateToken(userID, email, "access", time.Duration(j.expiryHours)*time.Hour)
+	return j.GenerateSessionTokenPair(TokenSubject{UserID: userID, Email: email}, 0)
+}
+
+// GenerateSessionTokenPair generates a token pair bound to a login session via the "sid" claim.
+// Roles and permissions only go into the access token; refreshing re-reads them.
+func (j *JWTManager) GenerateSessionTokenPair(subject TokenSubject, sessionID uint) (*TokenPair, error) {
+	accessToken, err := j.generateToken(subject, TokenTypeAccess, sessionID, time.Duration(j.expiryHours)*time.Hour)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := j.generateToken(userID, email, "refresh", time.Duration(j.refreshExpiryHours)*time.Hour)
+	refreshSubject := TokenSubject{UserID: subject.UserID, Email: subject.Email}
+	refreshToken, err := j.generateToken(refreshSubject, TokenTypeRefresh, sessionID, time.Duration(j.refreshExpiryHours)*time.Hour)
 	if err != nil {
 		return nil, err
 	}
@@ -58,34 +138,74 @@ func (j *JWTManager) GenerateTokenPair(userID uint, email string) (*TokenPair, e
 
 // GenerateToken generates a new JWT token for a user (backwards compatibility)
 func (j *JWTManager) GenerateToken(userID uint, email string) (string, error) {
-	return j.generateToken(userID, email, "access", time.Duration(j.expiryHours)*time.Hour)
+	return j.generateToken(TokenSubject{UserID: userID, Email: email}, TokenTypeAccess, 0, time.Duration(j.expiryHours)*time.Hour)
+}
+
+// GenerateMFAPendingToken generates a short-lived token proving the password step of a login succeeded.
+// It can only be exchanged for a token pair once the second factor has been verified.
+func (j *JWTManager) GenerateMFAPendingToken(userID uint, email string) (string, error) {
+	return j.generateToken(TokenSubject{UserID: userID, Email: email}, TokenTypeMFAPending, 0, MFAPendingTokenExpiry)
 }
 
 // generateToken is the internal method for generating tokens
-func (j *JWTManager) generateToken(userID uint, email, tokenType string, expiry time.Duration) (string, error) {
+func (j *JWTManager) generateToken(subject TokenSubject, tokenType string, sessionID uint, expiry time.Duration) (string, error) {
+	now := time.Now()
 	claims := JWTClaims{
-		UserID:    userID,
-		Email:     email,
-		TokenType: tokenType,
+		UserID:        subject.UserID,
+		Email:         subject.Email,
+		TokenType:     tokenType,
+		SessionID:     sessionID,
+		IssuedAtNanos: now.UnixNano(),
+		Roles:         subject.Roles,
+		Permissions:   subject.Permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(), // jti, keeps tokens issued in the same second distinct
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	j.mu.RLock()
+	key := j.activeKey
+	j.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signKey)
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT token and returns the claims.
+// The verification key is picked by the "kid" header; tokens issued before
+// kids were stamped are checked against every live key with a matching algorithm.
 func (j *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error) {
+	keys := j.verificationKeys()
+
+	validMethods := make([]string, 0, len(keys))
+	for _, key := range keys {
+		validMethods = append(validMethods, key.Method.Alg())
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+			for _, key := range keys {
+				if key.ID == kid && key.Method.Alg() == token.Method.Alg() {
+					return key.verifyKey, nil
+				}
+			}
+			return nil, fmt.Errorf("unknown signing key: %s", kid)
 		}
-		return []byte(j.secretKey), nil
-	})
+
+		candidates := jwt.VerificationKeySet{}
+		for _, key := range keys {
+			if key.Method.Alg() == token.Method.Alg() {
+				candidates.Keys = append(candidates.Keys, key.verifyKey)
+			}
+		}
+		return candidates, nil
+	}, jwt.WithValidMethods(validMethods))
 
 	if err != nil {
 		return nil, err
@@ -103,6 +223,108 @@ func (j *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error) {
 	return claims, nil
 }
 
+// JWKS returns the public keys that other services can use to verify tokens.
+// Retired keys stay published until they retire. Symmetric keys are never published,
+// so an HS256 manager returns an empty set.
+func (j *JWTManager) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range j.verificationKeys() {
+		if jwk, ok := key.JWK(); ok {
+			set.Keys = append(set.Keys, *jwk)
+		}
+	}
+	return set
+}
+
+// ActiveKeyID returns the kid of the key currently used for signing
+func (j *JWTManager) ActiveKeyID() string {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.activeKey.ID
+}
+
+// Rotate makes newKey the signing key. The previous active key keeps verifying
+// the tokens it signed until gracePeriod has elapsed. Rotating to the key that is
+// already active is a no-op, so configuration reloads can call this unconditionally.
+func (j *JWTManager) Rotate(newKey *SigningKey, gracePeriod time.Duration) error {
+	if newKey.ID == "" {
+		return fmt.Errorf("signing key must have a key ID to be rotated in")
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.activeKey.ID == newKey.ID {
+		return nil
+	}
+
+	previous := j.activeKey
+	if previous.ID != "" {
+		j.retiredKeys[previous.ID] = retiredKey{key: previous, retiresAt: time.Now().Add(gracePeriod)}
+	}
+	delete(j.retiredKeys, newKey.ID)
+	j.activeKey = newKey
+	j.pruneRetiredKeys()
+
+	return nil
+}
+
+// AddRetiredKey registers a key that only verifies tokens until retiresAt,
+// e.g. the previous key from configuration after a restart.
+func (j *JWTManager) AddRetiredKey(key *SigningKey, retiresAt time.Time) error {
+	if key.ID == "" {
+		return fmt.Errorf("retired signing key must have a key ID")
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if key.ID == j.activeKey.ID {
+		return fmt.Errorf("key %s is the active signing key", key.ID)
+	}
+	j.retiredKeys[key.ID] = retiredKey{key: key, retiresAt: retiresAt}
+	j.pruneRetiredKeys()
+
+	return nil
+}
+
+// verificationKeys returns the active key followed by every retired key that has not retired yet
+func (j *JWTManager) verificationKeys() []*SigningKey {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	now := time.Now()
+	retired := make([]retiredKey, 0, len(j.retiredKeys))
+	for _, rk := range j.retiredKeys {
+		if now.Before(rk.retiresAt) {
+			retired = append(retired, rk)
+		}
+	}
+	// Most recently retired first, so the JWKS order is stable
+	sort.Slice(retired, func(a, b int) bool { return retired[a].retiresAt.After(retired[b].retiresAt) })
+
+	keys := []*SigningKey{j.activeKey}
+	for _, rk := range retired {
+		keys = append(keys, rk.key)
+	}
+	return keys
+}
+
+// pruneRetiredKeys drops keys past their retirement date. Callers must hold the write lock.
+func (j *JWTManager) pruneRetiredKeys() {
+	now := time.Now()
+	for kid, rk := range j.retiredKeys {
+		if !now.Before(rk.retiresAt) {
+			delete(j.retiredKeys, kid)
+		}
+	}
+}
+
+// ExpiryHours returns the access token expiry hours
+func (j *JWTManager) ExpiryHours() int {
+	return j.expiryHours
+}
+
 // RefreshExpiryHours returns the refresh token expiry hours
 func (j *JWTManager) RefreshExpiryHours() int {
 	return j.refreshExpiryHours
@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signTestToken signs access token claims with key, stamping kid unless it is empty
+func signTestToken(t *testing.T, key *SigningKey, kid string) string {
+	t.Helper()
+	claims := JWTClaims{
+		UserID:    1,
+		TokenType: TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func assertValid(t *testing.T, manager *JWTManager, token string, want bool) {
+	t.Helper()
+	_, err := manager.ValidateToken(token)
+	if (err == nil) != want {
+		t.Errorf("ValidateToken error = %v, want valid %t", err, want)
+	}
+}
+
+func TestRotateKeepsRetiredKeyUntilGracePeriodEnds(t *testing.T) {
+	oldKey, newKey := newTestSigningKey(t, AlgorithmES256), newTestSigningKey(t, AlgorithmES256)
+	manager := NewJWTManagerWithKey(oldKey, 1)
+	oldToken, err := manager.GenerateToken(1, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Rotate(newKey, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if manager.ActiveKeyID() != newKey.ID {
+		t.Errorf("active kid = %s, want %s", manager.ActiveKeyID(), newKey.ID)
+	}
+	newToken, _ := manager.GenerateToken(1, "ada@example.com")
+	assertValid(t, manager, newToken, true)
+	assertValid(t, manager, oldToken, true)
+	if keys := manager.JWKS().Keys; len(keys) != 2 || keys[0].Kid != newKey.ID || keys[1].Kid != oldKey.ID {
+		t.Errorf("JWKS during the grace period = %+v, want the new key then the old one", keys)
+	}
+
+	// Once the grace period is over, the old key neither verifies nor is published
+	manager.retiredKeys[oldKey.ID] = retiredKey{key: oldKey, retiresAt: time.Now().Add(-time.Second)}
+	assertValid(t, manager, oldToken, false)
+	assertValid(t, manager, newToken, true)
+	if keys := manager.JWKS().Keys; len(keys) != 1 || keys[0].Kid != newKey.ID {
+		t.Errorf("JWKS after the grace period = %+v, want only the new key", keys)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	key := newTestSigningKey(t, AlgorithmEdDSA)
+	manager := NewJWTManagerWithKey(key, 1)
+
+	// Rotating to the active key, e.g. on a configuration reload, changes nothing
+	if err := manager.Rotate(key, time.Hour); err != nil || len(manager.retiredKeys) != 0 {
+		t.Errorf("Rotate to the active key = %v, retired %d keys", err, len(manager.retiredKeys))
+	}
+	if err := manager.Rotate(&SigningKey{Method: jwt.SigningMethodEdDSA}, time.Hour); err == nil {
+		t.Error("a key without kid was rotated in")
+	}
+
+	// Rotating back to a retired key makes it active again instead of retiring it twice
+	other := newTestSigningKey(t, AlgorithmEdDSA)
+	_ = manager.Rotate(other, time.Hour)
+	_ = manager.Rotate(key, time.Hour)
+	if _, retired := manager.retiredKeys[key.ID]; retired || manager.ActiveKeyID() != key.ID {
+		t.Errorf("key %s is active %t and retired %t", key.ID, manager.ActiveKeyID() == key.ID, retired)
+	}
+}
+
+func TestAddRetiredKey(t *testing.T) {
+	previous, current := newTestSigningKey(t, AlgorithmES256), newTestSigningKey(t, AlgorithmES256)
+	oldToken := signTestToken(t, previous, previous.ID)
+
+	// After a restart, the previous key comes from configuration
+	manager := NewJWTManagerWithKey(current, 1)
+	assertValid(t, manager, oldToken, false)
+	if err := manager.AddRetiredKey(previous, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	assertValid(t, manager, oldToken, true)
+
+	if err := manager.AddRetiredKey(current, time.Now().Add(time.Hour)); err == nil {
+		t.Error("the active key was added as retired")
+	}
+	if err := manager.AddRetiredKey(&SigningKey{Method: jwt.SigningMethodES256}, time.Now().Add(time.Hour)); err == nil {
+		t.Error("a retired key without kid was added")
+	}
+	if err := manager.AddRetiredKey(previous, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	assertValid(t, manager, oldToken, false)
+}
+
+func TestValidateTokenKeySelection(t *testing.T) {
+	active, retired, stranger := newTestSigningKey(t, AlgorithmES256), newTestSigningKey(t, AlgorithmES256), newTestSigningKey(t, AlgorithmES256)
+	manager := NewJWTManagerWithKey(active, 1)
+	if err := manager.AddRetiredKey(retired, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"active key", signTestToken(t, active, active.ID), true},
+		{"retired key", signTestToken(t, retired, retired.ID), true},
+		{"unknown kid", signTestToken(t, stranger, stranger.ID), false},
+		{"known kid, other key", signTestToken(t, stranger, active.ID), false},
+		{"kid of the other live key", signTestToken(t, retired, active.ID), false},
+		// Tokens from before kids were stamped are tried against every live key
+		{"no kid, active key", signTestToken(t, active, ""), true},
+		{"no kid, retired key", signTestToken(t, retired, ""), true},
+		{"no kid, unknown key", signTestToken(t, stranger, ""), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValid(t, manager, tt.token, tt.valid)
+		})
+	}
+}
+
+func TestValidateTokenRejectsAlgorithmSwap(t *testing.T) {
+	rsaKey := newTestSigningKey(t, AlgorithmRS256)
+	manager := NewJWTManagerWithKey(rsaKey, 1)
+
+	// HS256 with the public key as the secret, the classic confusion attack
+	publicDER, err := x509.MarshalPKIXPublicKey(rsaKey.verifyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	hmacKey := &SigningKey{Method: jwt.SigningMethodHS256, signKey: publicPEM}
+
+	edKey := newTestSigningKey(t, AlgorithmEdDSA)
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, JWTClaims{UserID: 1}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS256 keyed with the public key", signTestToken(t, hmacKey, rsaKey.ID)},
+		{"HS256 without kid", signTestToken(t, hmacKey, "")},
+		{"EdDSA under the RSA kid", signTestToken(t, edKey, rsaKey.ID)},
+		{"none", unsigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := manager.ValidateToken(tt.token)
+			if err == nil {
+				t.Fatal("token was accepted")
+			}
+			if !strings.Contains(err.Error(), "signing method") {
+				t.Errorf("rejected with %v, want the algorithm to be refused", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// internal/pkg/login_attempts.go
+package pkg
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AttemptState tracks failed logins for one key (an account or a client IP)
+type AttemptState struct {
+	Failures     int       // Failures in the current window
+	FirstFailure time.Time // Start of the current window
+	Lockouts     int       // Lockouts so far, drives the exponential backoff
+	LockedUntil  time.Time
+}
+
+// LoginAttemptStore keeps failed login counters. Implementations must be safe for
+// concurrent use. The in-memory implementation only covers a single instance;
+// plug in a shared backend (e.g. Redis) when running several.
+type LoginAttemptStore interface {
+	// Get returns the state for key, or the zero state if there is none
+	Get(ctx context.Context, key string) (AttemptState, error)
+	// Update atomically applies fn to the state for key and returns the result
+	Update(ctx context.Context, key string, fn func(state *AttemptState)) (AttemptState, error)
+	// Delete forgets key
+	Delete(ctx context.Context, key string) error
+}
+
+// attemptEntry is a stored state and the last time it changed
+type attemptEntry struct {
+	state     AttemptState
+	updatedAt time.Time
+}
+
+// MemoryAttemptStore is an in-memory LoginAttemptStore. Entries that have not changed
+// for the retention period are evicted, which also resets their backoff.
+type MemoryAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]attemptEntry
+	retention time.Duration
+	stop      chan struct{}
+}
+
+// NewMemoryAttemptStore creates an in-memory store that evicts stale entries every cleanupInterval
+func NewMemoryAttemptStore(cleanupInterval, retention time.Duration) *MemoryAttemptStore {
+	s := &MemoryAttemptStore{
+		entries:   make(map[string]attemptEntry),
+		retention: retention,
+		stop:      make(chan struct{}),
+	}
+	go s.evictLoop(cleanupInterval)
+	return s
+}
+
+func (s *MemoryAttemptStore) Get(ctx context.Context, key string) (AttemptState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key].state, nil
+}
+
+func (s *MemoryAttemptStore) Update(ctx context.Context, key string, fn func(state *AttemptState)) (AttemptState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[key]
+	fn(&entry.state)
+	entry.updatedAt = time.Now()
+	s.entries[key] = entry
+	return entry.state, nil
+}
+
+func (s *MemoryAttemptStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Close stops the eviction goroutine
+func (s *MemoryAttemptStore) Close() {
+	close(s.stop)
+}
+
+func (s *MemoryAttemptStore) evictLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evictStale()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *MemoryAttemptStore) evictStale() {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range s.entries {
+		if now.Before(entry.state.LockedUntil) {
+			continue
+		}
+		if now.Sub(entry.updatedAt) >= s.retention {
+			delete(s.entries, key)
+		}
+	}
+}
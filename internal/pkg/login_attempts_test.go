@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryAttemptStore(t *testing.T) {
+	store := NewMemoryAttemptStore(time.Hour, time.Hour)
+	defer store.Close()
+	ctx := context.Background()
+
+	state, err := store.Update(ctx, "account:ada", func(state *AttemptState) { state.Failures++ })
+	if err != nil || state.Failures != 1 {
+		t.Fatalf("Update = %+v, %v", state, err)
+	}
+	state, _ = store.Update(ctx, "account:ada", func(state *AttemptState) { state.Failures++ })
+	if got, _ := store.Get(ctx, "account:ada"); got != state || got.Failures != 2 {
+		t.Errorf("Get = %+v, want %+v", got, state)
+	}
+
+	if err := store.Delete(ctx, "account:ada"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Get(ctx, "account:ada"); got != (AttemptState{}) {
+		t.Errorf("deleted key returned %+v", got)
+	}
+}
+
+func TestMemoryAttemptStoreEvictsStaleEntries(t *testing.T) {
+	store := NewMemoryAttemptStore(time.Hour, time.Hour)
+	defer store.Close()
+	ctx := context.Background()
+	stale := time.Now().Add(-2 * time.Hour)
+
+	_, _ = store.Update(ctx, "stale", func(state *AttemptState) { state.Lockouts = 3 })
+	_, _ = store.Update(ctx, "locked", func(state *AttemptState) { state.LockedUntil = time.Now().Add(time.Minute) })
+	_, _ = store.Update(ctx, "recent", func(state *AttemptState) { state.Failures = 1 })
+	for _, key := range []string{"stale", "locked"} {
+		entry := store.entries[key]
+		entry.updatedAt = stale
+		store.entries[key] = entry
+	}
+	store.evictStale()
+
+	// A stale entry takes its backoff with it; a running lockout is kept however old
+	if _, ok := store.entries["stale"]; ok {
+		t.Error("stale entry kept")
+	}
+	for _, key := range []string{"locked", "recent"} {
+		if _, ok := store.entries[key]; !ok {
+			t.Errorf("entry %q evicted", key)
+		}
+	}
+}
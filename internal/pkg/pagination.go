@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// SortField is one key of a multi-field sort, e.g. "-created_at" is {created_at, Desc}
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// PageRequest selects a page of a list. Cursor and Offset are alternatives:
+// a cursor continues after the last item of the previous page.
+type PageRequest struct {
+	Limit     int
+	Offset    int
+	Cursor    string
+	Sort      []SortField
+	WithTotal bool // Count the matching items; costs an extra query
+}
+
+// Page is the envelope every paginated list is returned in
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      *int64 `json:"total,omitempty"` // Omitted when the count was not requested
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
+	HasMore    bool   `json:"has_more"`
+}
+
+// MapPage converts the items of a page, e.g. from models to response types
+func MapPage[T, U any](page *Page[T], fn func(*T) U) *Page[U] {
+	items := make([]U, len(page.Items))
+	for i := range page.Items {
+		items[i] = fn(&page.Items[i])
+	}
+	return &Page[U]{
+		Items:      items,
+		Total:      page.Total,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}
+}
+
+// Normalize applies the default limit, caps it at MaxPageLimit and rejects
+// requests that combine a cursor with an offset
+func (r *PageRequest) Normalize() error {
+	if r.Limit <= 0 {
+		r.Limit = DefaultPageLimit
+	}
+	if r.Limit > MaxPageLimit {
+		r.Limit = MaxPageLimit
+	}
+	if r.Offset < 0 {
+		return NewValidationError("offset", r.Offset, "Offset must not be negative")
+	}
+	if r.Cursor != "" && r.Offset > 0 {
+		return NewValidationError("cursor", r.Cursor, "Use either cursor or offset, not both")
+	}
+	return nil
+}
+
+// ParseSort parses a comma-separated sort expression such as "-created_at,name".
+// A leading "-" sorts descending. Whether a field may be sorted by is up to the caller.
+func ParseSort(raw string) ([]SortField, error) {
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if field.Field == "" {
+			return nil, NewValidationError("sort", raw, "Invalid sort expression %q", raw)
+		}
+		if seen[field.Field] {
+			return nil, NewValidationError("sort", raw, "Field %s is sorted by more than once", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// FormatSort is the inverse of ParseSort
+func FormatSort(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		} else {
+			parts[i] = field.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Cursor holds the sort key values of the last item of a page. It is bound to
+// the sort it was created for, so it cannot be replayed with another order.
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// EncodeCursor returns the opaque string form of a cursor
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor created by EncodeCursor
+func DecodeCursor(raw string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, NewValidationError("cursor", raw, "Invalid cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, NewValidationError("cursor", raw, "Invalid cursor")
+	}
+	return cursor, nil
+}
@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	fields, err := ParseSort(" -created_at, name ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SortField{{Field: "created_at", Desc: true}, {Field: "name"}}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("ParseSort = %v, want %v", fields, want)
+	}
+	if got := FormatSort(fields); got != "-created_at,name" {
+		t.Errorf("FormatSort = %q", got)
+	}
+
+	var validationErr *ValidationError
+	for _, raw := range []string{"-", "name,-name"} {
+		if _, err := ParseSort(raw); !errors.As(err, &validationErr) {
+			t.Errorf("ParseSort(%q) returned %v, want a ValidationError", raw, err)
+		}
+	}
+}
+
+func TestPageRequestNormalize(t *testing.T) {
+	req := PageRequest{}
+	if err := req.Normalize(); err != nil || req.Limit != DefaultPageLimit {
+		t.Errorf("empty request normalized to limit %d, %v", req.Limit, err)
+	}
+	req = PageRequest{Limit: MaxPageLimit + 1}
+	if err := req.Normalize(); err != nil || req.Limit != MaxPageLimit {
+		t.Errorf("oversized limit normalized to %d, %v", req.Limit, err)
+	}
+
+	var validationErr *ValidationError
+	for _, req := range []PageRequest{{Offset: -1}, {Cursor: "abc", Offset: 20}} {
+		if err := req.Normalize(); !errors.As(err, &validationErr) {
+			t.Errorf("Normalize(%+v) returned %v, want a ValidationError", req, err)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{Sort: "-created_at,id", Values: []string{"2024-05-01T10:00:00.123Z", "42"}}
+	decoded, err := DecodeCursor(EncodeCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, cursor) {
+		t.Errorf("DecodeCursor = %+v, want %+v", decoded, cursor)
+	}
+
+	var validationErr *ValidationError
+	for _, raw := range []string{"not base64!", "bm90IGpzb24"} {
+		if _, err := DecodeCursor(raw); !errors.As(err, &validationErr) {
+			t.Errorf("DecodeCursor(%q) returned %v, want a ValidationError", raw, err)
+		}
+	}
+}
@@ -2,19 +2,216 @@
 package pkg
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword hashes a plain text password
+// Supported password hashing algorithms
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// ErrPasswordMismatch is returned when a password does not match its hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHasher hashes new passwords with the configured algorithm and verifies
+// hashes made by any supported algorithm, so stored hashes can be upgraded on login
+type PasswordHasher interface {
+	// Hash returns the encoded hash of password
+	Hash(password string) (string, error)
+	// Verify returns nil if password matches encodedHash, ErrPasswordMismatch if not
+	Verify(encodedHash, password string) error
+	// NeedsRehash reports whether encodedHash uses another algorithm or weaker parameters than configured
+	NeedsRehash(encodedHash string) bool
+}
+
+// Argon2Params are the argon2id cost parameters
+type Argon2Params struct {
+	Memory     uint32 // KiB
+	Iterations uint32
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// DefaultArgon2Params follow the RFC 9106 recommendation for memory constrained environments
+var DefaultArgon2Params = Argon2Params{
+	Memory:     64 * 1024,
+	Iterations: 3,
+	Threads:    2,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+type passwordHasher struct {
+	algorithm  string
+	argon2     Argon2Params
+	bcryptCost int
+}
+
+// NewPasswordHasher creates a hasher that hashes with algorithm ("argon2id" or "bcrypt")
+func NewPasswordHasher(algorithm string, argon2Params Argon2Params, bcryptCost int) (PasswordHasher, error) {
+	switch algorithm {
+	case "", PasswordAlgorithmArgon2id:
+		algorithm = PasswordAlgorithmArgon2id
+		if argon2Params.Memory == 0 || argon2Params.Iterations == 0 || argon2Params.Threads == 0 {
+			return nil, NewConfigurationError("ARGON2_MEMORY_KIB", "positive integer", "argon2id memory, iterations and parallelism must be positive")
+		}
+		if argon2Params.SaltLength == 0 {
+			argon2Params.SaltLength = DefaultArgon2Params.SaltLength
+		}
+		if argon2Params.KeyLength == 0 {
+			argon2Params.KeyLength = DefaultArgon2Params.KeyLength
+		}
+	case PasswordAlgorithmBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, NewConfigurationError("BCRYPT_COST", "integer", "BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, NewConfigurationError("PASSWORD_HASH_ALGORITHM", "string", "unsupported password hash algorithm: %s", algorithm)
+	}
+
+	return &passwordHasher{
+		algorithm:  algorithm,
+		argon2:     argon2Params,
+		bcryptCost: bcryptCost,
+	}, nil
+}
+
+// defaultPasswordHasher backs HashPassword and CheckPassword
+var defaultPasswordHasher = &passwordHasher{
+	algorithm:  PasswordAlgorithmArgon2id,
+	argon2:     DefaultArgon2Params,
+	bcryptCost: bcrypt.DefaultCost,
+}
+
+// HashPassword hashes a plain text password with argon2id and the default parameters
 func HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	return defaultPasswordHasher.Hash(password)
+}
+
+// CheckPassword compares a hashed password (argon2id or bcrypt) with a plain text password
+func CheckPassword(hashedPassword, password string) error {
+	return defaultPasswordHasher.Verify(hashedPassword, password)
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.algorithm == PasswordAlgorithmBcrypt {
+		hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashedBytes), nil
+	}
+
+	salt := make([]byte, h.argon2.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	return string(hashedBytes), nil
+	key := argon2.IDKey([]byte(password), salt, h.argon2.Iterations, h.argon2.Memory, h.argon2.Threads, h.argon2.KeyLength)
+	return encodeArgon2id(h.argon2, salt, key), nil
 }
 
-// CheckPassword compares a hashed password with a plain text password
-func CheckPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func (h *passwordHasher) Verify(encodedHash, password string) error {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(encodedHash)
+		if err != nil {
+			return err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Threads, params.KeyLength)
+		if subtle.ConstantTimeCompare(key, candidate) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	case isBcryptHash(encodedHash):
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	default:
+		return fmt.Errorf("unsupported password hash format")
+	}
+}
+
+func (h *passwordHasher) NeedsRehash(encodedHash string) bool {
+	if h.algorithm == PasswordAlgorithmBcrypt {
+		if !isBcryptHash(encodedHash) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		return err != nil || cost < h.bcryptCost
+	}
+
+	if !strings.HasPrefix(encodedHash, "$argon2id$") {
+		return true
+	}
+	params, _, _, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.argon2.Memory ||
+		params.Iterations < h.argon2.Iterations ||
+		params.Threads < h.argon2.Threads ||
+		params.KeyLength < h.argon2.KeyLength
+}
+
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
+
+// encodeArgon2id formats a hash as a PHC string: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func encodeArgon2id(params Argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory, params.Iterations, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+// decodeArgon2id parses a PHC string produced by encodeArgon2id
+func decodeArgon2id(encodedHash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
 }
@@ -0,0 +1,173 @@
+// internal/pkg/password_policy.go
+package pkg
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Password policy rule names, reported in ValidationError.Violations
+const (
+	RuleMinLength    = "min_length"
+	RuleUppercase    = "uppercase"
+	RuleLowercase    = "lowercase"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RulePersonalInfo = "personal_info"
+	RuleBreached     = "breached"
+)
+
+// minPersonalInfoLength keeps very short names (e.g. "Al") from rejecting half of all passwords
+const minPersonalInfoLength = 3
+
+// BreachedPasswordChecker reports whether a password appears in a breach corpus
+type BreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// PasswordPolicy describes the rules new passwords must follow
+type PasswordPolicy struct {
+	MinLength            int
+	RequireUppercase     bool
+	RequireLowercase     bool
+	RequireDigit         bool
+	RequireSymbol        bool
+	DisallowPersonalInfo bool                    // Reject passwords containing the user's name or email
+	Breached             BreachedPasswordChecker // Nil disables the breach check
+}
+
+// PasswordOwner is the personal information a password must not contain
+type PasswordOwner struct {
+	Name  string
+	Email string
+}
+
+// Validate checks password against every rule and returns a ValidationError listing all failed rules
+func (p *PasswordPolicy) Validate(password string, owner PasswordOwner) error {
+	var violations []Violation
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, Violation{RuleMinLength, fmt.Sprintf("Password must be at least %d characters long", p.MinLength)})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUppercase && !hasUpper {
+		violations = append(violations, Violation{RuleUppercase, "Password must contain an uppercase letter"})
+	}
+	if p.RequireLowercase && !hasLower {
+		violations = append(violations, Violation{RuleLowercase, "Password must contain a lowercase letter"})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{RuleDigit, "Password must contain a digit"})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{RuleSymbol, "Password must contain a symbol"})
+	}
+
+	if p.DisallowPersonalInfo && containsPersonalInfo(password, owner) {
+		violations = append(violations, Violation{RulePersonalInfo, "Password must not contain your name or email address"})
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return NewInternalServerError(err, "Failed to check password against breach corpus")
+		}
+		if breached {
+			violations = append(violations, Violation{RuleBreached, "Password has appeared in a data breach, please choose another one"})
+		}
+	}
+
+	if len(violations) > 0 {
+		return NewRuleValidationError("password", "***", violations, "Password does not meet the password policy")
+	}
+	return nil
+}
+
+// containsPersonalInfo reports whether password contains the email, its local part or a name part
+func containsPersonalInfo(password string, owner PasswordOwner) bool {
+	lowered := strings.ToLower(password)
+
+	candidates := strings.Fields(strings.ToLower(owner.Name))
+	if email := strings.ToLower(strings.TrimSpace(owner.Email)); email != "" {
+		candidates = append(candidates, email)
+		if local, _, ok := strings.Cut(email, "@"); ok {
+			candidates = append(candidates, local)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if utf8.RuneCountInString(candidate) >= minPersonalInfoLength && strings.Contains(lowered, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// HIBPCorpus checks passwords against a local copy of the Have I Been Pwned password list,
+// stored as one file per SHA-1 prefix (as written by the PwnedPasswordsDownloader):
+// <dir>/<first 5 hex chars>.txt with lines "<remaining 35 hex chars>:<count>"
+type HIBPCorpus struct {
+	dir string
+}
+
+// NewHIBPCorpus opens a prefix-indexed corpus directory
+func NewHIBPCorpus(dir string) (*HIBPCorpus, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, NewFileNotFoundError(dir, "breached password corpus %s not found", dir)
+		}
+		return nil, fmt.Errorf("failed to open breached password corpus %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, NewConfigurationError("PASSWORD_BREACHED_CORPUS_DIR", "directory", "%s is not a directory", dir)
+	}
+	return &HIBPCorpus{dir: dir}, nil
+}
+
+// IsBreached looks the password's SHA-1 suffix up in its prefix file. A missing prefix
+// file means no breached password has that prefix.
+func (c *HIBPCorpus) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		entry, _, _ := strings.Cut(line, ":")
+		if strings.EqualFold(entry, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
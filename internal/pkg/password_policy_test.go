@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testCorpus is a prefix-file corpus holding "password" (SHA-1 5BAA61E4...)
+const testCorpus = "testdata/hibp"
+
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a ValidationError", err)
+	}
+	rules := make([]string, len(validationErr.Violations))
+	for i, violation := range validationErr.Violations {
+		if violation.Message == "" {
+			t.Errorf("violation %q has no message", violation.Rule)
+		}
+		rules[i] = violation.Rule
+	}
+	return rules
+}
+
+func TestPasswordPolicyRules(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:            10,
+		RequireUppercase:     true,
+		RequireLowercase:     true,
+		RequireDigit:         true,
+		RequireSymbol:        true,
+		DisallowPersonalInfo: true,
+	}
+	owner := PasswordOwner{Name: "Ada Lovelace", Email: "countess@example.com"}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"valid", "Correct-horse-9", nil},
+		{"too short", "Sh0rt-pw", []string{RuleMinLength}},
+		{"length counts characters, not bytes", "Ünïcödé-pw1", nil},
+		{"no uppercase", "correct-horse-9", []string{RuleUppercase}},
+		{"no lowercase", "CORRECT-HORSE-9", []string{RuleLowercase}},
+		{"no digit", "Correct-horse-x", []string{RuleDigit}},
+		{"no symbol", "Correcthorse9", []string{RuleSymbol}},
+		{"space counts as symbol", "Correct horse 9", nil},
+		{"name part", "Lovelace-Rules-9", []string{RulePersonalInfo}},
+		{"email local part", "Countess-Rules-9", []string{RulePersonalInfo}},
+		{"every violation at once", "ada", []string{RuleMinLength, RuleUppercase, RuleDigit, RuleSymbol, RulePersonalInfo}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violatedRules(t, policy.Validate(tt.password, owner))
+			if len(got) != len(tt.want) {
+				t.Fatalf("violations %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("violations %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyViolationMessages(t *testing.T) {
+	corpus, err := NewHIBPCorpus(testCorpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &PasswordPolicy{
+		MinLength:            12,
+		RequireUppercase:     true,
+		RequireDigit:         true,
+		RequireSymbol:        true,
+		DisallowPersonalInfo: true,
+		Breached:             corpus,
+	}
+
+	err = policy.Validate("password", PasswordOwner{Name: "Pass Word"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "password" || validationErr.Value != "***" {
+		t.Fatalf("got %#v, want a ValidationError for the masked password", err)
+	}
+	want := []Violation{
+		{RuleMinLength, "Password must be at least 12 characters long"},
+		{RuleUppercase, "Password must contain an uppercase letter"},
+		{RuleDigit, "Password must contain a digit"},
+		{RuleSymbol, "Password must contain a symbol"},
+		{RulePersonalInfo, "Password must not contain your name or email address"},
+		{RuleBreached, "Password has appeared in a data breach, please choose another one"},
+	}
+	if len(validationErr.Violations) != len(want) {
+		t.Fatalf("violations %+v, want %+v", validationErr.Violations, want)
+	}
+	for i, violation := range validationErr.Violations {
+		if violation != want[i] {
+			t.Errorf("violation %d = %+v, want %+v", i, violation, want[i])
+		}
+	}
+}
+
+func TestPasswordPolicyIgnoresShortNameParts(t *testing.T) {
+	policy := &PasswordPolicy{DisallowPersonalInfo: true}
+	if err := policy.Validate("always-albatross", PasswordOwner{Name: "Al Bo"}); err != nil {
+		t.Errorf("name parts under %d characters were matched: %v", minPersonalInfoLength, err)
+	}
+}
+
+func TestHIBPCorpus(t *testing.T) {
+	corpus, err := NewHIBPCorpus(testCorpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"password", true},
+		{"not-breached-347444", false},          // Same prefix file, suffix not listed
+		{"correct horse battery staple", false}, // No prefix file
+	}
+	for _, tt := range tests {
+		breached, err := corpus.IsBreached(tt.password)
+		if err != nil || breached != tt.want {
+			t.Errorf("IsBreached(%q) = %t, %v, want %t", tt.password, breached, err, tt.want)
+		}
+	}
+
+	policy := &PasswordPolicy{MinLength: 8, Breached: corpus}
+	if got := violatedRules(t, policy.Validate("password", PasswordOwner{})); len(got) != 1 || got[0] != RuleBreached {
+		t.Errorf("breached password gave violations %v", got)
+	}
+}
+
+func TestNewHIBPCorpusErrors(t *testing.T) {
+	var fileNotFoundErr *FileNotFoundError
+	if _, err := NewHIBPCorpus(filepath.Join(t.TempDir(), "missing")); !errors.As(err, &fileNotFoundErr) {
+		t.Errorf("missing directory returned %v, want a FileNotFoundError", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var configErr *ConfigurationError
+	if _, err := NewHIBPCorpus(file); !errors.As(err, &configErr) {
+		t.Errorf("a file instead of a directory returned %v, want a ConfigurationError", err)
+	}
+}
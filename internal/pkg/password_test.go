@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params keep the tests fast; production defaults are far more expensive
+var testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Threads: 1}
+
+func newTestHasher(t *testing.T, algorithm string, params Argon2Params, bcryptCost int) PasswordHasher {
+	t.Helper()
+	hasher, err := NewPasswordHasher(algorithm, params, bcryptCost)
+	if err != nil {
+		t.Fatalf("NewPasswordHasher(%q): %v", algorithm, err)
+	}
+	return hasher
+}
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	hasher := newTestHasher(t, PasswordAlgorithmArgon2id, testArgon2Params, 0)
+
+	hash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected hash format: %s", hash)
+	}
+	if err := hasher.Verify(hash, "correct horse"); err != nil {
+		t.Errorf("Verify with the right password: %v", err)
+	}
+	if err := hasher.Verify(hash, "wrong horse"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Verify with a wrong password returned %v, want ErrPasswordMismatch", err)
+	}
+
+	other, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Error("two hashes of the same password are equal; the salt is not random")
+	}
+}
+
+func TestBcryptHashAndVerify(t *testing.T) {
+	hasher := newTestHasher(t, PasswordAlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost)
+
+	hash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hasher.Verify(hash, "correct horse"); err != nil {
+		t.Errorf("Verify with the right password: %v", err)
+	}
+	if err := hasher.Verify(hash, "wrong horse"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Verify with a wrong password returned %v, want ErrPasswordMismatch", err)
+	}
+}
+
+func TestVerifyAcceptsEitherAlgorithm(t *testing.T) {
+	argon2Hasher := newTestHasher(t, PasswordAlgorithmArgon2id, testArgon2Params, 0)
+	bcryptHasher := newTestHasher(t, PasswordAlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost)
+
+	bcryptHash, err := bcryptHasher.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := argon2Hasher.Verify(bcryptHash, "secret"); err != nil {
+		t.Errorf("argon2id hasher failed to verify a bcrypt hash: %v", err)
+	}
+
+	argon2Hash, err := argon2Hasher.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcryptHasher.Verify(argon2Hash, "secret"); err != nil {
+		t.Errorf("bcrypt hasher failed to verify an argon2id hash: %v", err)
+	}
+
+	if err := argon2Hasher.Verify("plaintext", "plaintext"); err == nil || errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Verify of an unknown format returned %v, want a format error", err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak := newTestHasher(t, PasswordAlgorithmArgon2id, testArgon2Params, 0)
+	strong := newTestHasher(t, PasswordAlgorithmArgon2id, Argon2Params{Memory: 2048, Iterations: 2, Threads: 1}, 0)
+	bcryptLow := newTestHasher(t, PasswordAlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost)
+	bcryptHigh := newTestHasher(t, PasswordAlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost+1)
+
+	weakHash, _ := weak.Hash("secret")
+	strongHash, _ := strong.Hash("secret")
+	bcryptHash, _ := bcryptLow.Hash("secret")
+
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{"same argon2id parameters", weak, weakHash, false},
+		{"stronger argon2id hash", weak, strongHash, false},
+		{"weaker argon2id parameters", strong, weakHash, true},
+		{"bcrypt hash under argon2id", weak, bcryptHash, true},
+		{"same bcrypt cost", bcryptLow, bcryptHash, false},
+		{"lower bcrypt cost", bcryptHigh, bcryptHash, true},
+		{"argon2id hash under bcrypt", bcryptLow, weakHash, true},
+		{"malformed argon2id hash", weak, "$argon2id$v=19$broken", true},
+	}
+	for _, tt := range tests {
+		if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+			t.Errorf("%s: NeedsRehash = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewPasswordHasherRejectsInvalidSettings(t *testing.T) {
+	if _, err := NewPasswordHasher("md5", testArgon2Params, 0); err == nil {
+		t.Error("unsupported algorithm was accepted")
+	}
+	if _, err := NewPasswordHasher(PasswordAlgorithmArgon2id, Argon2Params{}, 0); err == nil {
+		t.Error("zero argon2id parameters were accepted")
+	}
+	if _, err := NewPasswordHasher(PasswordAlgorithmBcrypt, Argon2Params{}, bcrypt.MaxCost+1); err == nil {
+		t.Error("out of range bcrypt cost was accepted")
+	}
+}
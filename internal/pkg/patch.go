@@ -0,0 +1,54 @@
+package pkg
+
+import (
+	"errors"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	ContentTypeMergePatch = "application/merge-patch+json" // RFC 7396
+	ContentTypeJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+// PatchContentTypes are the patch formats accepted by PATCH endpoints
+var PatchContentTypes = []string{ContentTypeMergePatch, ContentTypeJSONPatch}
+
+// Patch is a partial update in one of the PatchContentTypes
+type Patch struct {
+	ContentType string
+	Body        []byte
+}
+
+// NewPatch checks the content type and returns the patch
+func NewPatch(contentType string, body []byte) (Patch, error) {
+	if contentType != ContentTypeMergePatch && contentType != ContentTypeJSONPatch {
+		return Patch{}, NewUnsupportedMediaTypeError(contentType, PatchContentTypes, "Unsupported patch format %q", contentType)
+	}
+	return Patch{ContentType: contentType, Body: body}, nil
+}
+
+// Apply applies the patch to a JSON document and returns the patched document.
+// A failed "test" operation is a ConflictError, any other failure an InvalidInputError.
+func (p Patch) Apply(document []byte) ([]byte, error) {
+	if p.ContentType == ContentTypeMergePatch {
+		patched, err := jsonpatch.MergePatch(document, p.Body)
+		if err != nil {
+			return nil, NewInvalidInputError("Invalid merge patch: %v", err)
+		}
+		return patched, nil
+	}
+
+	operations, err := jsonpatch.DecodePatch(p.Body)
+	if err != nil {
+		return nil, NewInvalidInputError("Invalid JSON patch: %v", err)
+	}
+	patched, err := operations.Apply(document)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, NewConflictError("test operation failed", "JSON patch test failed: %v", err)
+		}
+		return nil, NewInvalidInputError("Failed to apply JSON patch: %v", err)
+	}
+	return patched, nil
+}
@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const patchDocument = `{"name":"Ada","email":"ada@example.com","phone":"123"}`
+
+func TestNewPatchRejectsUnsupportedContentType(t *testing.T) {
+	var mediaTypeErr *UnsupportedMediaTypeError
+	if _, err := NewPatch("application/json", []byte(`{}`)); !errors.As(err, &mediaTypeErr) {
+		t.Errorf("NewPatch returned %v, want an UnsupportedMediaTypeError", err)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	patch, err := NewPatch(ContentTypeMergePatch, []byte(`{"name":"Grace","phone":null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := patch.Apply([]byte(patchDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDocument(t, patched, map[string]interface{}{"name": "Grace", "email": "ada@example.com"})
+}
+
+func TestJSONPatch(t *testing.T) {
+	patch, err := NewPatch(ContentTypeJSONPatch, []byte(`[
+		{"op":"test","path":"/name","value":"Ada"},
+		{"op":"replace","path":"/name","value":"Grace"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := patch.Apply([]byte(patchDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDocument(t, patched, map[string]interface{}{"name": "Grace", "email": "ada@example.com", "phone": "123"})
+}
+
+func TestJSONPatchFailedTestIsConflict(t *testing.T) {
+	patch, err := NewPatch(ContentTypeJSONPatch, []byte(`[
+		{"op":"test","path":"/name","value":"Someone else"},
+		{"op":"replace","path":"/name","value":"Grace"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conflictErr *ConflictError
+	if _, err := patch.Apply([]byte(patchDocument)); !errors.As(err, &conflictErr) {
+		t.Errorf("Apply returned %v, want a ConflictError", err)
+	}
+}
+
+func TestInvalidPatchIsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"malformed merge patch", ContentTypeMergePatch, `{"name":`},
+		{"malformed JSON patch", ContentTypeJSONPatch, `{"op":"replace"}`},
+		{"path that does not exist", ContentTypeJSONPatch, `[{"op":"replace","path":"/missing/field","value":1}]`},
+	}
+	for _, tt := range tests {
+		patch, err := NewPatch(tt.contentType, []byte(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var invalidErr *InvalidInputError
+		if _, err := patch.Apply([]byte(patchDocument)); !errors.As(err, &invalidErr) {
+			t.Errorf("%s: Apply returned %v, want an InvalidInputError", tt.name, err)
+		}
+	}
+}
+
+func assertDocument(t *testing.T, document []byte, want map[string]interface{}) {
+	t.Helper()
+	var got map[string]interface{}
+	if err := json.Unmarshal(document, &got); err != nil {
+		t.Fatalf("patched document is not JSON: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("patched document is %s, want %v", document, want)
+		return
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("patched document is %s, want %v", document, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formats of the bulk import and export endpoints
+const (
+	RecordFormatCSV    = "csv"
+	RecordFormatNDJSON = "ndjson" // One JSON object per line
+)
+
+// RecordContentTypes maps each record format to its media type
+var RecordContentTypes = map[string]string{
+	RecordFormatCSV:    "text/csv",
+	RecordFormatNDJSON: "application/x-ndjson",
+}
+
+// RecordFormatFromContentType returns the record format of a media type
+func RecordFormatFromContentType(contentType string) (string, error) {
+	for format, mediaType := range RecordContentTypes {
+		if contentType == mediaType {
+			return format, nil
+		}
+	}
+	return "", NewUnsupportedMediaTypeError(contentType, []string{RecordContentTypes[RecordFormatCSV], RecordContentTypes[RecordFormatNDJSON]}, "Unsupported record format %q", contentType)
+}
+
+// maxRecordLine limits the length of one NDJSON line
+const maxRecordLine = 1 << 20
+
+// RecordReader streams flat records, keyed by field name, from a CSV or NDJSON source.
+// Read returns io.EOF after the last record. A record that cannot be parsed is reported
+// as an InvalidInputError, and reading can go on with the next record.
+type RecordReader interface {
+	Read() (map[string]string, error)
+}
+
+// NewRecordReader returns a reader for the format. CSV sources start with a header row.
+func NewRecordReader(format string, r io.Reader) (RecordReader, error) {
+	switch format {
+	case RecordFormatCSV:
+		reader := csv.NewReader(r)
+		reader.TrimLeadingSpace = true
+		header, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, NewInvalidInputError("CSV input is empty")
+			}
+			return nil, NewInvalidInputError("Invalid CSV header: %v", err)
+		}
+		for i := range header {
+			header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+		}
+		return &csvRecordReader{reader: reader, header: header}, nil
+	case RecordFormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxRecordLine)
+		return &ndjsonRecordReader{scanner: scanner}, nil
+	default:
+		return nil, NewValidationError("format", format, "Format must be csv or ndjson")
+	}
+}
+
+type csvRecordReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func (r *csvRecordReader) Read() (map[string]string, error) {
+	fields, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, NewInvalidInputError("Invalid CSV record: %v", parseErr.Err)
+		}
+		return nil, err
+	}
+
+	record := make(map[string]string, len(r.header))
+	for i, name := range r.header {
+		record[name] = fields[i]
+	}
+	return record, nil
+}
+
+type ndjsonRecordReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *ndjsonRecordReader) Read() (map[string]string, error) {
+	var line []byte
+	for len(line) == 0 {
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = bytes.TrimSpace(r.scanner.Bytes())
+	}
+
+	var object map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, NewInvalidInputError("Invalid JSON record: %v", err)
+	}
+
+	record := make(map[string]string, len(object))
+	for name, value := range object {
+		switch value := value.(type) {
+		case nil:
+		case string:
+			record[strings.ToLower(name)] = value
+		case json.Number, bool:
+			record[strings.ToLower(name)] = fmt.Sprint(value)
+		default:
+			return nil, NewInvalidInputError("Field %s must be a string", name)
+		}
+	}
+	return record, nil
+}
+
+// RecordWriter streams records with a fixed set of fields as CSV or NDJSON.
+// Values are written as is; times use RFC 3339 and nil is an empty CSV field or JSON null.
+type RecordWriter interface {
+	Write(values []interface{}) error
+	Flush() error
+}
+
+// NewRecordWriter returns a writer for the format. CSV output starts with a header row.
+func NewRecordWriter(format string, w io.Writer, fields []string) (RecordWriter, error) {
+	switch format {
+	case RecordFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(fields); err != nil {
+			return nil, err
+		}
+		return &csvRecordWriter{writer: writer}, nil
+	case RecordFormatNDJSON:
+		return &ndjsonRecordWriter{writer: bufio.NewWriter(w), fields: fields}, nil
+	default:
+		return nil, NewValidationError("format", format, "Format must be csv or ndjson")
+	}
+}
+
+type csvRecordWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvRecordWriter) Write(values []interface{}) error {
+	fields := make([]string, len(values))
+	for i, value := range values {
+		switch value := value.(type) {
+		case nil:
+		case *time.Time:
+			if value != nil {
+				fields[i] = value.UTC().Format(time.RFC3339)
+			}
+		case time.Time:
+			fields[i] = value.UTC().Format(time.RFC3339)
+		default:
+			fields[i] = fmt.Sprint(value)
+		}
+	}
+	return w.writer.Write(fields)
+}
+
+func (w *csvRecordWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonRecordWriter struct {
+	writer *bufio.Writer
+	fields []string
+}
+
+// Write writes the fields in their given order, which a map would not keep
+func (w *ndjsonRecordWriter) Write(values []interface{}) error {
+	var line bytes.Buffer
+	line.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		name, _ := json.Marshal(w.fields[i])
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339)
+		}
+		if t, ok := value.(*time.Time); ok && t != nil {
+			value = t.UTC().Format(time.RFC3339)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		line.Write(name)
+		line.WriteByte(':')
+		line.Write(data)
+	}
+	line.WriteString("}\n")
+	_, err := w.writer.Write(line.Bytes())
+	return err
+}
+
+func (w *ndjsonRecordWriter) Flush() error {
+	return w.writer.Flush()
+}
@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAllRecords(t *testing.T, reader RecordReader) ([]map[string]string, []error) {
+	t.Helper()
+	var records []map[string]string
+	var errs []error
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, errs
+		}
+		var invalidErr *InvalidInputError
+		if errors.As(err, &invalidErr) {
+			errs = append(errs, err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestCSVRecordReader(t *testing.T) {
+	input := "Name, EMAIL ,phone\nAda,ada@example.com,123\n\"Lovelace, Ada\",lovelace@example.com,\n"
+	reader, err := NewRecordReader(RecordFormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, errs := readAllRecords(t, reader)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	want := []map[string]string{
+		{"name": "Ada", "email": "ada@example.com", "phone": "123"},
+		{"name": "Lovelace, Ada", "email": "lovelace@example.com", "phone": ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+}
+
+func TestCSVRecordReaderReportsBadRows(t *testing.T) {
+	input := "name,email\nAda,ada@example.com\nonly one field\nGrace,grace@example.com\n"
+	reader, err := NewRecordReader(RecordFormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, errs := readAllRecords(t, reader)
+	if len(records) != 2 || len(errs) != 1 {
+		t.Errorf("got %d records and %d errors, want 2 records and 1 error", len(records), len(errs))
+	}
+}
+
+func TestCSVRecordReaderRejectsEmptyInput(t *testing.T) {
+	var invalidErr *InvalidInputError
+	if _, err := NewRecordReader(RecordFormatCSV, strings.NewReader("")); !errors.As(err, &invalidErr) {
+		t.Errorf("NewRecordReader returned %v, want an InvalidInputError", err)
+	}
+}
+
+func TestNDJSONRecordReader(t *testing.T) {
+	input := `{"Name":"Ada","email":"ada@example.com","age":36,"admin":true,"phone":null}
+
+{"name":"Grace"
+{"name":"Grace","tags":["a"]}
+{"name":"Grace","email":"grace@example.com"}
+`
+	reader, err := NewRecordReader(RecordFormatNDJSON, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, errs := readAllRecords(t, reader)
+	want := []map[string]string{
+		{"name": "Ada", "email": "ada@example.com", "age": "36", "admin": "true"},
+		{"name": "Grace", "email": "grace@example.com"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+	// The truncated object and the array field
+	if len(errs) != 2 {
+		t.Errorf("got %d errors, want 2: %v", len(errs), errs)
+	}
+}
+
+func TestRecordReaderRejectsUnknownFormat(t *testing.T) {
+	var validationErr *ValidationError
+	if _, err := NewRecordReader("xml", strings.NewReader("")); !errors.As(err, &validationErr) {
+		t.Errorf("NewRecordReader returned %v, want a ValidationError", err)
+	}
+}
+
+func TestRecordWriters(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	fields := []string{"id", "name", "created_at", "deleted_at"}
+	values := []interface{}{uint(7), `Ada "the first"`, created, (*time.Time)(nil)}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{RecordFormatCSV, "id,name,created_at,deleted_at\n7,\"Ada \"\"the first\"\"\",2024-05-01T10:00:00Z,\n"},
+		{RecordFormatNDJSON, `{"id":7,"name":"Ada \"the first\"","created_at":"2024-05-01T10:00:00Z","deleted_at":null}` + "\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		writer, err := NewRecordWriter(tt.format, &buf, fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Write(values); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s output = %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestRecordFormatFromContentType(t *testing.T) {
+	if format, err := RecordFormatFromContentType("text/csv"); err != nil || format != RecordFormatCSV {
+		t.Errorf("text/csv = %q, %v", format, err)
+	}
+	if format, err := RecordFormatFromContentType("application/x-ndjson"); err != nil || format != RecordFormatNDJSON {
+		t.Errorf("application/x-ndjson = %q, %v", format, err)
+	}
+	var mediaTypeErr *UnsupportedMediaTypeError
+	if _, err := RecordFormatFromContentType("application/json"); !errors.As(err, &mediaTypeErr) {
+		t.Errorf("application/json returned %v, want an UnsupportedMediaTypeError", err)
+	}
+}
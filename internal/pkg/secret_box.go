@@ -0,0 +1,95 @@
+// internal/pkg/secret_box.go
+package pkg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// SecretBox encrypts small secrets (e.g. TOTP seeds) before they are stored, using AES-256-GCM.
+// Values are sealed with the current key; previous keys are only used to open values sealed
+// before a key rotation.
+type SecretBox struct {
+	aeads []cipher.AEAD // Current key first
+}
+
+// NewSecretBox creates a secret box from a 32 byte key and any number of previous keys
+func NewSecretBox(key []byte, previous ...[]byte) (*SecretBox, error) {
+	box := &SecretBox{}
+	for _, k := range append([][]byte{key}, previous...) {
+		if len(k) != 32 {
+			return nil, fmt.Errorf("secret box key must be 32 bytes, got %d", len(k))
+		}
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		box.aeads = append(box.aeads, aead)
+	}
+	return box, nil
+}
+
+// ParseSecretBoxKey decodes a base64 encoded 32 byte key read from the given configuration key
+func ParseSecretBoxKey(configKey, value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != 32 {
+		return nil, NewConfigurationError(configKey, "base64 encoded 32 byte key", "%s must be a base64 encoded 32 byte key (e.g. openssl rand -base64 32)", configKey)
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext with the current key and returns base64(nonce || ciphertext)
+func (b *SecretBox) Seal(plaintext string) (string, error) {
+	aead := b.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal with the current or a previous key
+func (b *SecretBox) Open(sealed string) (string, error) {
+	plaintext, _, err := b.open(sealed)
+	return plaintext, err
+}
+
+// Reseal seals a value again with the current key if it was sealed with a previous one.
+// It reports whether the value changed, so callers only store it when needed.
+func (b *SecretBox) Reseal(sealed string) (string, bool, error) {
+	plaintext, current, err := b.open(sealed)
+	if err != nil || current {
+		return sealed, false, err
+	}
+	resealed, err := b.Seal(plaintext)
+	if err != nil {
+		return sealed, false, err
+	}
+	return resealed, true, nil
+}
+
+// open decrypts sealed and reports whether the current key was used
+func (b *SecretBox) open(sealed string) (string, bool, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid sealed secret: %w", err)
+	}
+	for i, aead := range b.aeads {
+		if len(raw) < aead.NonceSize() {
+			return "", false, fmt.Errorf("invalid sealed secret: too short")
+		}
+		nonce, ciphertext := raw[:aead.NonceSize()], raw[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, ciphertext, nil); err == nil {
+			return string(plaintext), i == 0, nil
+		}
+	}
+	return "", false, fmt.Errorf("failed to decrypt secret with any configured key")
+}
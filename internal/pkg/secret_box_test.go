@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestSecretBoxSealAndOpen(t *testing.T) {
+	box, err := NewSecretBox(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := box.Seal("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := box.Open(sealed)
+	if err != nil || opened != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Open = %q, %v", opened, err)
+	}
+
+	other, _ := box.Seal("JBSWY3DPEHPK3PXP")
+	if other == sealed {
+		t.Error("sealing twice gave the same value; the nonce is not random")
+	}
+
+	otherBox, _ := NewSecretBox(bytes.Repeat([]byte{2}, 32))
+	if _, err := otherBox.Open(sealed); err == nil {
+		t.Error("a box with another key opened the value")
+	}
+	if _, err := box.Open("not base64!"); err == nil {
+		t.Error("a malformed value was opened")
+	}
+}
+
+func TestSecretBoxRotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	oldBox, _ := NewSecretBox(oldKey)
+	sealed, err := oldBox.Seal("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	box, err := NewSecretBox(newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := box.Open(sealed); err != nil || opened != "secret" {
+		t.Fatalf("value sealed with the previous key: Open = %q, %v", opened, err)
+	}
+
+	resealed, changed, err := box.Reseal(sealed)
+	if err != nil || !changed {
+		t.Fatalf("Reseal = changed %t, %v; want a changed value", changed, err)
+	}
+	newOnly, _ := NewSecretBox(newKey)
+	if opened, err := newOnly.Open(resealed); err != nil || opened != "secret" {
+		t.Errorf("resealed value does not open with the new key alone: %q, %v", opened, err)
+	}
+
+	// Values sealed with the current key are left alone
+	if again, changed, err := box.Reseal(resealed); err != nil || changed || again != resealed {
+		t.Errorf("Reseal of a current value = %q, changed %t, %v", again, changed, err)
+	}
+}
+
+func TestNewSecretBoxRejectsShortKeys(t *testing.T) {
+	if _, err := NewSecretBox(make([]byte, 16)); err == nil {
+		t.Error("a 16 byte key was accepted")
+	}
+	if _, err := NewSecretBox(make([]byte, 32), make([]byte, 31)); err == nil {
+		t.Error("a short previous key was accepted")
+	}
+}
+
+func TestParseSecretBoxKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	parsed, err := ParseSecretBoxKey("MFA_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(key))
+	if err != nil || !bytes.Equal(parsed, key) {
+		t.Errorf("ParseSecretBoxKey = %x, %v", parsed, err)
+	}
+
+	var configErr *ConfigurationError
+	for _, value := range []string{"not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := ParseSecretBoxKey("MFA_ENCRYPTION_KEY", value); !errors.As(err, &configErr) {
+			t.Errorf("ParseSecretBoxKey(%q) returned %v, want a ConfigurationError", value, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// internal/pkg/signing_key.go
+package pkg
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for RS256 signing
+const minRSAKeyBits = 2048
+
+// SigningKey holds the key material used to sign and verify tokens
+type SigningKey struct {
+	ID        string // Key ID published as the "kid" header and in the JWKS
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACSigningKey creates an HS256 signing key from a shared secret
+func NewHMACSigningKey(secret string) *SigningKey {
+	return &SigningKey{
+		ID:        hmacKeyID(secret),
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// NewSigningKey builds a signing key for the configured algorithm.
+// HS256 uses the shared secret; every other algorithm loads a PEM private key from privateKeyPath.
+func NewSigningKey(algorithm, secret, privateKeyPath string) (*SigningKey, error) {
+	if algorithm == "" || algorithm == AlgorithmHS256 {
+		if secret == "" {
+			return nil, NewConfigurationError("JWT_SECRET", "string", "JWT_SECRET is required for %s signing", AlgorithmHS256)
+		}
+		return NewHMACSigningKey(secret), nil
+	}
+
+	if privateKeyPath == "" {
+		return nil, NewConfigurationError("JWT_PRIVATE_KEY_PATH", "string", "JWT_PRIVATE_KEY_PATH is required for %s signing", algorithm)
+	}
+	return LoadSigningKey(algorithm, privateKeyPath)
+}
+
+// LoadSigningKey reads a PEM encoded private key from disk
+func LoadSigningKey(algorithm, path string) (*SigningKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, NewFileNotFoundError(path, "signing key file %s not found", path)
+		}
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+	return ParseSigningKeyPEM(algorithm, pemBytes)
+}
+
+// ParseSigningKeyPEM parses a PEM encoded private key for the given algorithm
+func ParseSigningKeyPEM(algorithm string, pemBytes []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("signing key is not PEM encoded")
+	}
+
+	privateKey, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{signKey: privateKey}
+	switch strings.TrimSpace(algorithm) {
+	case AlgorithmRS256:
+		rsaKey, ok := privateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an RSA private key", AlgorithmRS256)
+		}
+		if rsaKey.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.verifyKey = &rsaKey.PublicKey
+	case AlgorithmES256:
+		ecKey, ok := privateKey.(*ecdsa.PrivateKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%s requires an ECDSA P-256 private key", AlgorithmES256)
+		}
+		key.Method = jwt.SigningMethodES256
+		key.verifyKey = &ecKey.PublicKey
+	case AlgorithmEdDSA:
+		edKey, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an Ed25519 private key", AlgorithmEdDSA)
+		}
+		key.Method = jwt.SigningMethodEdDSA
+		key.verifyKey = edKey.Public()
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	jwk, ok := key.JWK()
+	if !ok {
+		return nil, fmt.Errorf("failed to derive public key for %s", algorithm)
+	}
+	kid, err := jwk.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	key.ID = kid
+
+	return key, nil
+}
+
+// hmacKeyID derives a stable key ID for a shared secret without publishing a plain hash of it
+func hmacKeyID(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("jwt-key-id"))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// IsAsymmetric reports whether the key has a public half that can be shared
+func (k *SigningKey) IsAsymmetric() bool {
+	_, isSecret := k.verifyKey.([]byte)
+	return !isSecret
+}
+
+// parsePrivateKey accepts PKCS#8, PKCS#1 (RSA) and SEC 1 (EC) encoded keys
+func parsePrivateKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+}
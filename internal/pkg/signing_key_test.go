@@ -0,0 +1,288 @@
+package pkg
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RSA key generation is slow, so the test keys are generated once per run
+var (
+	testRSAKeyOnce sync.Once
+	testRSAKey     *rsa.PrivateKey
+)
+
+func rsaTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testRSAKeyOnce.Do(func() {
+		testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	})
+	if testRSAKey == nil {
+		t.Fatal("failed to generate RSA test key")
+	}
+	return testRSAKey
+}
+
+// generateTestKey returns a new private key of the kind the algorithm signs with
+func generateTestKey(t *testing.T, algorithm string) crypto.Signer {
+	t.Helper()
+	var key crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		return rsaTestKey(t)
+	case AlgorithmES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("no test key for %s", algorithm)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func pkcs8PEM(t *testing.T, key crypto.Signer) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func newTestSigningKey(t *testing.T, algorithm string) *SigningKey {
+	t.Helper()
+	key, err := ParseSigningKeyPEM(algorithm, pkcs8PEM(t, generateTestKey(t, algorithm)))
+	if err != nil {
+		t.Fatalf("ParseSigningKeyPEM(%s): %v", algorithm, err)
+	}
+	return key
+}
+
+func TestSignAndVerify(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		key       func(t *testing.T) *SigningKey
+	}{
+		{AlgorithmHS256, func(t *testing.T) *SigningKey { return NewHMACSigningKey("a-long-shared-secret") }},
+		{AlgorithmRS256, func(t *testing.T) *SigningKey { return newTestSigningKey(t, AlgorithmRS256) }},
+		{AlgorithmES256, func(t *testing.T) *SigningKey { return newTestSigningKey(t, AlgorithmES256) }},
+		{AlgorithmEdDSA, func(t *testing.T) *SigningKey { return newTestSigningKey(t, AlgorithmEdDSA) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			key := tt.key(t)
+			manager := NewJWTManagerWithKey(key, 1)
+
+			tokenString, err := manager.GenerateToken(7, "ada@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, &JWTClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Header["alg"] != tt.algorithm || token.Header["kid"] != key.ID {
+				t.Errorf("header %v, want alg %s and kid %s", token.Header, tt.algorithm, key.ID)
+			}
+
+			claims, err := manager.ValidateToken(tokenString)
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			if claims.UserID != 7 || claims.Email != "ada@example.com" || claims.TokenType != TokenTypeAccess {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+
+			// A changed payload breaks the signature
+			parts := strings.Split(tokenString, ".")
+			parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"user_id":1,"token_type":"access"}`))
+			if _, err := manager.ValidateToken(strings.Join(parts, ".")); err == nil {
+				t.Error("token with a changed payload was accepted")
+			}
+		})
+	}
+}
+
+func TestParseSigningKeyPEMFormats(t *testing.T) {
+	ecKey := generateTestKey(t, AlgorithmES256).(*ecdsa.PrivateKey)
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		algorithm string
+		pem       []byte
+	}{
+		{"PKCS#1 RSA", AlgorithmRS256, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaTestKey(t))})},
+		{"PKCS#8 RSA", AlgorithmRS256, pkcs8PEM(t, rsaTestKey(t))},
+		{"SEC 1 EC", AlgorithmES256, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})},
+		{"PKCS#8 EC", AlgorithmES256, pkcs8PEM(t, ecKey)},
+		{"PKCS#8 Ed25519", AlgorithmEdDSA, pkcs8PEM(t, generateTestKey(t, AlgorithmEdDSA))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseSigningKeyPEM(tt.algorithm, tt.pem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.Method.Alg() != tt.algorithm || key.ID == "" || !key.IsAsymmetric() {
+				t.Errorf("got alg %s, kid %q, asymmetric %t", key.Method.Alg(), key.ID, key.IsAsymmetric())
+			}
+		})
+	}
+}
+
+func TestParseSigningKeyPEMRejects(t *testing.T) {
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		algorithm string
+		pem       []byte
+		want      string
+	}{
+		{"RSA under 2048 bits", AlgorithmRS256, pkcs8PEM(t, smallRSA), "at least 2048 bits"},
+		{"ES256 with P-384", AlgorithmES256, pkcs8PEM(t, p384), "P-256"},
+		{"ES256 with RSA", AlgorithmES256, pkcs8PEM(t, rsaTestKey(t)), "P-256"},
+		{"RS256 with EC", AlgorithmRS256, pkcs8PEM(t, p384), "RSA private key"},
+		{"EdDSA with EC", AlgorithmEdDSA, pkcs8PEM(t, p384), "Ed25519"},
+		{"unsupported algorithm", "PS256", pkcs8PEM(t, rsaTestKey(t)), "unsupported signing algorithm"},
+		{"not PEM", AlgorithmRS256, []byte("not a key"), "not PEM encoded"},
+		{"public key", AlgorithmRS256, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{0}}), "unsupported PEM block type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSigningKeyPEM(tt.algorithm, tt.pem)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// RFC 7638, section 3.1
+	rsaKey := JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		// Members outside the thumbprint must not change it
+		Use: "sig", Alg: AlgorithmRS256, Kid: "2011-04-29",
+	}
+	thumbprint, err := rsaKey.Thumbprint()
+	if err != nil || thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("RSA thumbprint = %q, %v", thumbprint, err)
+	}
+
+	if _, err := (&JWK{Kty: "oct"}).Thumbprint(); err == nil {
+		t.Error("thumbprint of a symmetric key was computed")
+	}
+}
+
+func TestEd25519KeyIDIsThumbprint(t *testing.T) {
+	// RFC 8037, appendix A.1 and A.3
+	seed, err := base64.RawURLEncoding.DecodeString("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseSigningKeyPEM(AlgorithmEdDSA, pkcs8PEM(t, ed25519.NewKeyFromSeed(seed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwk, _ := key.JWK()
+	if jwk.X != "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo" {
+		t.Errorf("x = %q", jwk.X)
+	}
+	if key.ID != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("kid = %q, want the RFC 8037 thumbprint", key.ID)
+	}
+}
+
+func TestJWKSDocument(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		members   map[string]int // Base64url members and their decoded length, 0 for any
+	}{
+		{AlgorithmRS256, map[string]int{"n": 256, "e": 0}},
+		{AlgorithmES256, map[string]int{"x": 32, "y": 32}},
+		{AlgorithmEdDSA, map[string]int{"x": 32}},
+	}
+	wantType := map[string][2]string{
+		AlgorithmRS256: {"RSA", ""},
+		AlgorithmES256: {"EC", "P-256"},
+		AlgorithmEdDSA: {"OKP", "Ed25519"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			key := newTestSigningKey(t, tt.algorithm)
+			encoded, err := json.Marshal(NewJWTManagerWithKey(key, 1).JWKS())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var document struct {
+				Keys []map[string]string `json:"keys"`
+			}
+			if err := json.Unmarshal(encoded, &document); err != nil {
+				t.Fatal(err)
+			}
+			if len(document.Keys) != 1 {
+				t.Fatalf("JWKS %s, want one key", encoded)
+			}
+			jwk := document.Keys[0]
+
+			want := map[string]string{"kty": wantType[tt.algorithm][0], "use": "sig", "alg": tt.algorithm, "kid": key.ID}
+			if crv := wantType[tt.algorithm][1]; crv != "" {
+				want["crv"] = crv
+			}
+			for member, length := range tt.members {
+				decoded, err := base64.RawURLEncoding.DecodeString(jwk[member])
+				if err != nil || len(decoded) == 0 || (length != 0 && len(decoded) != length) {
+					t.Errorf("member %q = %q is not %d bytes of base64url", member, jwk[member], length)
+				}
+				want[member] = jwk[member]
+			}
+			for member, value := range want {
+				if jwk[member] != value {
+					t.Errorf("member %q = %q, want %q", member, jwk[member], value)
+				}
+			}
+			// Nothing else is published, in particular no private member like "d"
+			if len(jwk) != len(want) {
+				t.Errorf("JWK %v has members beyond %v", jwk, want)
+			}
+		})
+	}
+
+	encoded, _ := json.Marshal(NewJWTManager("a-long-shared-secret", 1).JWKS())
+	if string(encoded) != `{"keys":[]}` {
+		t.Errorf("HS256 JWKS = %s, want an empty key set", encoded)
+	}
+}
@@ -0,0 +1,41 @@
+// internal/pkg/token_hash.go
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// TokenHasher derives the value stored for a secret token, so a leaked table
+// cannot be used to take over sessions. The pepper is a server-side secret
+// kept outside the database.
+type TokenHasher struct {
+	pepper []byte
+}
+
+// NewTokenHasher creates a token hasher keyed with the server pepper
+func NewTokenHasher(pepper string) (*TokenHasher, error) {
+	if pepper == "" {
+		return nil, NewConfigurationError("TOKEN_PEPPER", "string", "TOKEN_PEPPER is required to hash tokens at rest")
+	}
+	return &TokenHasher{pepper: []byte(pepper)}, nil
+}
+
+// Hash returns the hex encoded HMAC-SHA256 of the token
+func (h *TokenHasher) Hash(token string) string {
+	mac := hmac.New(sha256.New, h.pepper)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateOpaqueToken returns a URL-safe random token carrying 256 bits of entropy
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
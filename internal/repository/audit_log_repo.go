@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+)
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *model.AuditLog) error
+	ListByUser(ctx context.Context, userID uint) ([]model.AuditLog, error)
+	AnonymizeForUser(ctx context.Context, userID uint) error
+	WithTx(tx *gorm.DB) AuditLogRepository
+}
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db}
+}
+
+func (r *auditLogRepository) WithTx(tx *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{tx}
+}
+
+func (r *auditLogRepository) Create(ctx context.Context, entry *model.AuditLog) error {
+	if err := r.db.WithContext(ctx).Create(entry).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to write audit log entry %s for user %d", entry.Action, entry.UserID)
+	}
+	return nil
+}
+
+// ListByUser returns the audit trail of an account, newest first
+func (r *auditLogRepository) ListByUser(ctx context.Context, userID uint) ([]model.AuditLog, error) {
+	var entries []model.AuditLog
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to list audit log for user %d", userID)
+	}
+	return entries, nil
+}
+
+// AnonymizeForUser clears the personal data in the audit trail of an account: details of
+// entries about the user, and client IP and user agent of entries the user made or is about.
+// The entries themselves stay, so the trail keeps its shape.
+func (r *auditLogRepository) AnonymizeForUser(ctx context.Context, userID uint) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&model.AuditLog{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"ip_address": "", "user_agent": "", "details": ""}).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to anonymize audit log for user %d", userID)
+	}
+	if err := db.Model(&model.AuditLog{}).Where("actor_id = ?", userID).
+		Updates(map[string]interface{}{"ip_address": "", "user_agent": ""}).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to anonymize audit log entries made by user %d", userID)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+)
+
+type EmailChangeRequestRepository interface {
+	Create(ctx context.Context, request *model.EmailChangeRequest) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error)
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	InvalidateForUser(ctx context.Context, userID uint) error
+	WithTx(tx *gorm.DB) EmailChangeRequestRepository
+}
+
+type emailChangeRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailChangeRequestRepository(db *gorm.DB) EmailChangeRequestRepository {
+	return &emailChangeRequestRepository{db}
+}
+
+func (r *emailChangeRequestRepository) WithTx(tx *gorm.DB) EmailChangeRequestRepository {
+	return &emailChangeRequestRepository{tx}
+}
+
+func (r *emailChangeRequestRepository) Create(ctx context.Context, request *model.EmailChangeRequest) error {
+	if err := r.db.WithContext(ctx).Create(request).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to store email change request for user %d", request.UserID)
+	}
+	return nil
+}
+
+func (r *emailChangeRequestRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error) {
+	var request model.EmailChangeRequest
+
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkg.NewNotFoundError("email change request not found")
+		}
+		return nil, pkg.NewInternalServerError(err, "failed to get email change request")
+	}
+
+	return &request, nil
+}
+
+// MarkUsed consumes the request. It returns false when the request was already used.
+func (r *emailChangeRequestRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.EmailChangeRequest{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, pkg.NewInternalServerError(result.Error, "failed to mark email change request %d as used", id)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForUser consumes every outstanding email change request of the user
+func (r *emailChangeRequestRepository) InvalidateForUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Model(&model.EmailChangeRequest{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to invalidate email change requests for user %d", userID)
+	}
+	return nil
+}
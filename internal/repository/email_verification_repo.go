@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationTokenRepository interface {
+	Create(ctx context.Context, token *model.EmailVerificationToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailVerificationToken, error)
+	GetLatestForUser(ctx context.Context, userID uint) (*model.EmailVerificationToken, error)
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	InvalidateForUser(ctx context.Context, userID uint) error
+	WithTx(tx *gorm.DB) EmailVerificationTokenRepository
+}
+
+type emailVerificationTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationTokenRepository(db *gorm.DB) EmailVerificationTokenRepository {
+	return &emailVerificationTokenRepository{db}
+}
+
+func (r *emailVerificationTokenRepository) WithTx(tx *gorm.DB) EmailVerificationTokenRepository {
+	return &emailVerificationTokenRepository{tx}
+}
+
+func (r *emailVerificationTokenRepository) Create(ctx context.Context, token *model.EmailVerificationToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to store email verification token for user %d", token.UserID)
+	}
+	return nil
+}
+
+func (r *emailVerificationTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailVerificationToken, error) {
+	var token model.EmailVerificationToken
+
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkg.NewNotFoundError("email verification token not found")
+		}
+		return nil, pkg.NewInternalServerError(err, "failed to get email verification token")
+	}
+
+	return &token, nil
+}
+
+// GetLatestForUser returns the most recently issued token, used to throttle resends
+func (r *emailVerificationTokenRepository) GetLatestForUser(ctx context.Context, userID uint) (*model.EmailVerificationToken, error) {
+	var token model.EmailVerificationToken
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkg.NewNotFoundError("no email verification token for user %d", userID)
+		}
+		return nil, pkg.NewInternalServerError(err, "failed to get email verification token for user %d", userID)
+	}
+
+	return &token, nil
+}
+
+// MarkUsed consumes the token. It returns false when the token was already used.
+func (r *emailVerificationTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, pkg.NewInternalServerError(result.Error, "failed to mark email verification token %d as used", id)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForUser consumes every outstanding verification token of the user
+func (r *emailVerificationTokenRepository) InvalidateForUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Model(&model.EmailVerificationToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to invalidate email verification tokens for user %d", userID)
+	}
+	return nil
+}
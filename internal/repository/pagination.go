@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+)
+
+// sortKind tells how a sort key value is written to and read back from a cursor
+type sortKind int
+
+const (
+	sortString sortKind = iota
+	sortTime
+	sortUint
+)
+
+// sortColumn is a column a list may be sorted by
+type sortColumn[T any] struct {
+	Column string
+	Kind   sortKind
+	Value  func(item *T) any // The item's value of the column, for the next cursor
+}
+
+// sortColumns whitelists the sortable fields of a list by their API name.
+// Every list also needs an "id" entry, which breaks ties between equal keys.
+type sortColumns[T any] map[string]sortColumn[T]
+
+// resolve checks the requested sort against the whitelist and appends the id tie-breaker
+func (c sortColumns[T]) resolve(requested []pkg.SortField, fallback []pkg.SortField) ([]pkg.SortField, error) {
+	if len(requested) == 0 {
+		requested = fallback
+	}
+
+	fields := make([]pkg.SortField, 0, len(requested)+1)
+	hasID := false
+	for _, field := range requested {
+		if _, ok := c[field.Field]; !ok {
+			return nil, pkg.NewValidationError("sort", field.Field, "Cannot sort by %s", field.Field)
+		}
+		hasID = hasID || field.Field == "id"
+		fields = append(fields, field)
+	}
+	if !hasID {
+		fields = append(fields, pkg.SortField{Field: "id"})
+	}
+	return fields, nil
+}
+
+// paginate runs query for one page. query must already carry the filters; the total is
+// counted on it before the cursor, order, offset and limit are applied.
+func paginate[T any](ctx context.Context, query *gorm.DB, columns sortColumns[T], fallback []pkg.SortField, req pkg.PageRequest) (*pkg.Page[T], error) {
+	if err := req.Normalize(); err != nil {
+		return nil, err
+	}
+	sort, err := columns.resolve(req.Sort, fallback)
+	if err != nil {
+		return nil, err
+	}
+	sortKey := pkg.FormatSort(sort)
+
+	page := &pkg.Page[T]{Limit: req.Limit, Offset: req.Offset}
+	query = query.WithContext(ctx)
+
+	if req.WithTotal {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Model(new(T)).Count(&total).Error; err != nil {
+			return nil, pkg.NewInternalServerError(err, "failed to count items")
+		}
+		page.Total = &total
+	}
+
+	if req.Cursor != "" {
+		cursor, err := pkg.DecodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != sortKey || len(cursor.Values) != len(sort) {
+			return nil, pkg.NewValidationError("cursor", req.Cursor, "Cursor does not match the requested sort")
+		}
+		condition, args, err := keysetCondition(columns, sort, cursor.Values)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition, args...)
+	}
+
+	for _, field := range sort {
+		direction := "ASC"
+		if field.Desc {
+			direction = "DESC"
+		}
+		query = query.Order(columns[field.Field].Column + " " + direction)
+	}
+
+	var items []T
+	// One extra row tells whether there is a next page
+	if err := query.Offset(req.Offset).Limit(req.Limit + 1).Find(&items).Error; err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to list items")
+	}
+
+	if len(items) > req.Limit {
+		items = items[:req.Limit]
+		page.HasMore = true
+		page.NextCursor = pkg.EncodeCursor(pkg.Cursor{
+			Sort:   sortKey,
+			Values: cursorValues(columns, sort, &items[len(items)-1]),
+		})
+	}
+	if items == nil {
+		items = []T{}
+	}
+	page.Items = items
+	return page, nil
+}
+
+// keysetCondition selects the rows after the cursor for a mixed-direction sort:
+// (a > x) OR (a = x AND b < y) OR (a = x AND b = y AND id > z) ...
+func keysetCondition[T any](columns sortColumns[T], sort []pkg.SortField, raw []string) (string, []any, error) {
+	values := make([]any, len(sort))
+	for i, field := range sort {
+		value, err := parseCursorValue(columns[field.Field].Kind, raw[i])
+		if err != nil {
+			return "", nil, pkg.NewValidationError("cursor", raw[i], "Invalid cursor")
+		}
+		values[i] = value
+	}
+
+	var clauses []string
+	var args []any
+	for i, field := range sort {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, columns[sort[j].Field].Column+" = ?")
+			args = append(args, values[j])
+		}
+		operator := ">"
+		if field.Desc {
+			operator = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", columns[field.Field].Column, operator))
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args, nil
+}
+
+func cursorValues[T any](columns sortColumns[T], sort []pkg.SortField, item *T) []string {
+	values := make([]string, len(sort))
+	for i, field := range sort {
+		switch value := columns[field.Field].Value(item).(type) {
+		case time.Time:
+			values[i] = value.UTC().Format(time.RFC3339Nano)
+		case uint:
+			values[i] = strconv.FormatUint(uint64(value), 10)
+		default:
+			values[i] = fmt.Sprint(value)
+		}
+	}
+	return values
+}
+
+func parseCursorValue(kind sortKind, raw string) (any, error) {
+	switch kind {
+	case sortTime:
+		return time.Parse(time.RFC3339Nano, raw)
+	case sortUint:
+		value, err := strconv.ParseUint(raw, 10, 64)
+		return uint(value), err
+	default:
+		return raw, nil
+	}
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied search term
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
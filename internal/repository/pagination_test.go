@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+)
+
+func TestSortColumnsResolve(t *testing.T) {
+	sort, err := userSortColumns.resolve(nil, defaultUserSort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pkg.SortField{{Field: "created_at", Desc: true}, {Field: "id"}}
+	if !reflect.DeepEqual(sort, want) {
+		t.Errorf("default sort = %v, want %v", sort, want)
+	}
+
+	// An explicit id sort is kept as the tie-breaker instead of adding another
+	sort, err = userSortColumns.resolve([]pkg.SortField{{Field: "id", Desc: true}}, defaultUserSort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []pkg.SortField{{Field: "id", Desc: true}}; !reflect.DeepEqual(sort, want) {
+		t.Errorf("id sort = %v, want %v", sort, want)
+	}
+
+	var validationErr *pkg.ValidationError
+	if _, err := userSortColumns.resolve([]pkg.SortField{{Field: "password"}}, defaultUserSort); !errors.As(err, &validationErr) {
+		t.Errorf("sorting by password returned %v, want a ValidationError", err)
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	sort := []pkg.SortField{{Field: "name"}, {Field: "created_at", Desc: true}, {Field: "id"}}
+	created := time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC)
+
+	condition, args, err := keysetCondition(userSortColumns, sort, []string{"Ada", created.Format(time.RFC3339Nano), "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCondition := "((name > ?) OR (name = ? AND created_at < ?) OR (name = ? AND created_at = ? AND id > ?))"
+	if condition != wantCondition {
+		t.Errorf("condition = %s, want %s", condition, wantCondition)
+	}
+	wantArgs := []any{"Ada", "Ada", created, "Ada", created, uint(42)}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestKeysetConditionRejectsMalformedValues(t *testing.T) {
+	sort := []pkg.SortField{{Field: "created_at"}, {Field: "id"}}
+	var validationErr *pkg.ValidationError
+	for _, values := range [][]string{{"yesterday", "1"}, {"2024-05-01T10:00:00Z", "-1"}} {
+		if _, _, err := keysetCondition(userSortColumns, sort, values); !errors.As(err, &validationErr) {
+			t.Errorf("keysetCondition(%v) returned %v, want a ValidationError", values, err)
+		}
+	}
+}
+
+// The values written to a cursor must parse back to the same keys
+func TestCursorValuesRoundTrip(t *testing.T) {
+	user := &model.User{Name: "Ada"}
+	user.ID = 42
+	user.CreatedAt = time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60))
+
+	sort := []pkg.SortField{{Field: "created_at", Desc: true}, {Field: "name"}, {Field: "id"}}
+	values := cursorValues(userSortColumns, sort, user)
+	if want := []string{"2024-05-01T10:00:00.123456789Z", "Ada", "42"}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("cursorValues = %v, want %v", values, want)
+	}
+
+	_, args, err := keysetCondition(userSortColumns, sort, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created, ok := args[0].(time.Time); !ok || !created.Equal(user.CreatedAt) {
+		t.Errorf("created_at parsed back as %v, want %v", args[0], user.CreatedAt)
+	}
+	if id := args[len(args)-1]; id != user.ID {
+		t.Errorf("id parsed back as %v, want %d", id, user.ID)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got := escapeLike(`50%_off\`); got != `50\%\_off\\` {
+		t.Errorf("escapeLike = %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *model.PasswordResetToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error)
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	InvalidateForUser(ctx context.Context, userID uint) error
+	WithTx(tx *gorm.DB) PasswordResetTokenRepository
+}
+
+type passwordResetTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{db}
+}
+
+func (r *passwordResetTokenRepository) WithTx(tx *gorm.DB) PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{tx}
+}
+
+func (r *passwordResetTokenRepository) Create(ctx context.Context, token *model.PasswordResetToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to store password reset token for user %d", token.UserID)
+	}
+	return nil
+}
+
+func (r *passwordResetTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error) {
+	var token model.PasswordResetToken
+
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkg.NewNotFoundError("password reset token not found")
+		}
+		return nil, pkg.NewInternalServerError(err, "failed to get password reset token")
+	}
+
+	return &token, nil
+}
+
+// MarkUsed consumes the token. It returns false when the token was already used.
+func (r *passwordResetTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, pkg.NewInternalServerError(result.Error, "failed to mark password reset token %d as used", id)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForUser consumes every outstanding reset token of the user
+func (r *passwordResetTokenRepository) InvalidateForUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Model(&model.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to invalidate password reset tokens for user %d", userID)
+	}
+	return nil
+}
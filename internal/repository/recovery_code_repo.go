@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	CreateBatch(ctx context.Context, codes []model.RecoveryCode) error
+	Consume(ctx context.Context, userID uint, codeHash string) (bool, error)
+	DeleteForUser(ctx context.Context, userID uint) error
+	WithTx(tx *gorm.DB) RecoveryCodeRepository
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db}
+}
+
+func (r *recoveryCodeRepository) WithTx(tx *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{tx}
+}
+
+func (r *recoveryCodeRepository) CreateBatch(ctx context.Context, codes []model.RecoveryCode) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(&codes).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to store recovery codes for user %d", codes[0].UserID)
+	}
+	return nil
+}
+
+// Consume marks an unused recovery code as used. It returns false when no such code exists.
+func (r *recoveryCodeRepository) Consume(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, pkg.NewInternalServerError(result.Error, "failed to consume recovery code for user %d", userID)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *recoveryCodeRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to delete recovery codes for user %d", userID)
+	}
+	return nil
+}
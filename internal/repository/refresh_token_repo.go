@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+	WithTx(tx *gorm.DB) RefreshTokenRepository
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db}
+}
+
+func (r *refreshTokenRepository) WithTx(tx *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{tx}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to store refresh token for user %d", token.UserID)
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var refreshToken model.RefreshToken
+
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&refreshToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkg.NewNotFoundError("refresh token not found")
+		}
+		return nil, pkg.NewInternalServerError(err, "failed to get refresh token")
+	}
+
+	return &refreshToken, nil
+}
+
+// MarkUsed flags the token as exchanged. It returns false when the token was
+// already used or revoked, so two concurrent refreshes cannot both succeed.
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, pkg.NewInternalServerError(result.Error, "failed to mark refresh token %d as used", id)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	if err := r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to revoke refresh token family %s", familyID)
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to revoke refresh tokens for user %d", userID)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	List(ctx context.Context) ([]model.Role, error)
+	GetByName(ctx context.Context, name string) (*model.Role, error)
+	GetUserRoles(ctx context.Context, userID uint) ([]model.Role, error)
+	AssignToUser(ctx context.Context, userID, roleID uint) error
+	RemoveFromUser(ctx context.Context, userID, roleID uint) error
+	CountUsersWithRole(ctx context.Context, roleID uint) (int64, error)
+	WithTx(tx *gorm.DB) RoleRepository
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db}
+}
+
+func (r *roleRepository) WithTx(tx *gorm.DB) RoleRepository {
+	return &roleRepository{tx}
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]model.Role, error) {
+	var roles []model.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to list roles")
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*model.Role, error) {
+	var role model.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkg.NewNotFoundError("role %s not found", name)
+		}
+		return nil, pkg.NewInternalServerError(err, "failed to get role %s", name)
+	}
+	return &role, nil
+}
+
+// GetUserRoles returns the user's roles with their permissions
+func (r *roleRepository) GetUserRoles(ctx context.Context, userID uint) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.WithContext(ctx).
+		Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Find(&roles).Error
+	if err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to get roles for user %d", userID)
+	}
+	return roles, nil
+}
+
+// AssignToUser adds the role to the user. Assigning a role twice is a no-op.
+func (r *roleRepository) AssignToUser(ctx context.Context, userID, roleID uint) error {
+	err := r.db.WithContext(ctx).Table("user_roles").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]interface{}{"user_id": userID, "role_id": roleID}).Error
+	if err != nil {
+		return pkg.NewInternalServerError(err, "failed to assign role %d to user %d", roleID, userID)
+	}
+	return nil
+}
+
+func (r *roleRepository) RemoveFromUser(ctx context.Context, userID, roleID uint) error {
+	if err := r.db.WithContext(ctx).Exec("DELETE FROM user_roles WHERE user_id = ? AND role_id = ?", userID, roleID).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to remove role %d from user %d", roleID, userID)
+	}
+	return nil
+}
+
+// CountUsersWithRole counts the users (not deleted) holding the role. The counted rows are
+// locked until the transaction ends, so concurrent callers removing the role from different
+// users wait for each other and each see the other's change.
+func (r *roleRepository) CountUsersWithRole(ctx context.Context, roleID uint) (int64, error) {
+	// Postgres does not lock rows for an aggregate, so the rows are selected and counted here
+	var userIDs []uint
+	err := r.db.WithContext(ctx).Table("user_roles").
+		Joins("JOIN users ON users.id = user_roles.user_id AND users.deleted_at IS NULL").
+		Where("user_roles.role_id = ?", roleID).
+		Order("user_roles.user_id"). // Same lock order everywhere, so callers cannot deadlock
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Pluck("user_roles.user_id", &userIDs).Error
+	if err != nil {
+		return 0, pkg.NewInternalServerError(err, "failed to count users with role %d", roleID)
+	}
+	return int64(len(userIDs)), nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	GetByID(ctx context.Context, id uint) (*model.Session, error)
+	ListActiveByUser(ctx context.Context, userID uint) ([]model.Session, error)
+	ListByUser(ctx context.Context, userID uint) ([]model.Session, error)
+	TouchByFamily(ctx context.Context, familyID string, lastUsedAt, expiresAt time.Time) error
+	Revoke(ctx context.Context, id uint) error
+	RevokeByFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+	RevokeAllForUserExcept(ctx context.Context, userID, keepSessionID uint) error
+	WithTx(tx *gorm.DB) SessionRepository
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db}
+}
+
+func (r *sessionRepository) WithTx(tx *gorm.DB) SessionRepository {
+	return &sessionRepository{tx}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *model.Session) error {
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to create session for user %d", session.UserID)
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id uint) (*model.Session, error) {
+	var session model.Session
+
+	if err := r.db.WithContext(ctx).First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkg.NewNotFoundError("session with ID %d not found", id)
+		}
+		return nil, pkg.NewInternalServerError(err, "failed to get session by ID %d", id)
+	}
+
+	return &session, nil
+}
+
+// ListActiveByUser returns the sessions that are neither revoked nor expired, most recently used first
+func (r *sessionRepository) ListActiveByUser(ctx context.Context, userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to list sessions for user %d", userID)
+	}
+
+	return sessions, nil
+}
+
+// ListByUser returns every session of the user, including revoked and expired ones, newest first
+func (r *sessionRepository) ListByUser(ctx context.Context, userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions).Error; err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to list sessions for user %d", userID)
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionRepository) TouchByFamily(ctx context.Context, familyID string, lastUsedAt, expiresAt time.Time) error {
+	if err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("family_id = ?", familyID).
+		Updates(map[string]interface{}{"last_used_at": lastUsedAt, "expires_at": expiresAt}).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to update session for token family %s", familyID)
+	}
+	return nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to revoke session %d", id)
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeByFamily(ctx context.Context, familyID string) error {
+	if err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to revoke session for token family %s", familyID)
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to revoke sessions for user %d", userID)
+	}
+	return nil
+}
+
+// RevokeAllForUserExcept revokes every session of the user except keepSessionID
+func (r *sessionRepository) RevokeAllForUserExcept(ctx context.Context, userID, keepSessionID uint) error {
+	if err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return pkg.NewInternalServerError(err, "failed to revoke other sessions for user %d", userID)
+	}
+	return nil
+}
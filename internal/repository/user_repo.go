@@ -4,24 +4,53 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"your_project/internal/model"
 	"your_project/internal/pkg"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
 	GetByID(ctx context.Context, id uint) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
-	GetByRefreshToken(ctx context.Context, refreshToken string) (*model.User, error)
 	Create(ctx context.Context, user *model.User) error
 	Update(ctx context.Context, user *model.User) error
 	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context, filter UserFilter, page pkg.PageRequest) (*pkg.Page[model.User], error)
+	Restore(ctx context.Context, id uint) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time, limit int) ([]model.User, error)
+	Anonymize(ctx context.Context, id uint) (*model.User, error)
+	SetAvatar(ctx context.Context, id uint, key, thumbnailKey string) error
 	WithTx(tx *gorm.DB) UserRepository
 }
 
+// UserFilter narrows a user listing; zero values do not filter
+type UserFilter struct {
+	EmailPrefix    string
+	NameContains   string
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	IncludeDeleted bool
+	OnlyDeleted    bool
+}
+
+// userSortColumns are the fields users can be sorted by
+var userSortColumns = sortColumns[model.User]{
+	"id":         {Column: "id", Kind: sortUint, Value: func(u *model.User) any { return u.ID }},
+	"name":       {Column: "name", Kind: sortString, Value: func(u *model.User) any { return u.Name }},
+	"email":      {Column: "email", Kind: sortString, Value: func(u *model.User) any { return u.Email }},
+	"created_at": {Column: "created_at", Kind: sortTime, Value: func(u *model.User) any { return u.CreatedAt }},
+	"updated_at": {Column: "updated_at", Kind: sortTime, Value: func(u *model.User) any { return u.UpdatedAt }},
+}
+
+// defaultUserSort lists the newest users first
+var defaultUserSort = []pkg.SortField{{Field: "created_at", Desc: true}}
+
 type userRepository struct {
 	db *gorm.DB
 }
@@ -48,11 +77,12 @@ func (r *userRepository) GetByID(ctx context.Context, id uint) (*model.User, err
 	return &user, nil
 }
 
+// GetByEmail ignores case, like the unique index on lower(email)
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
 
 	// Pass the context to the GORM query
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("lower(email) = lower(?)", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, pkg.NewNotFoundError("user with email %s not found", email)
 		}
@@ -62,20 +92,6 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.U
 	return &user, nil
 }
 
-func (r *userRepository) GetByRefreshToken(ctx context.Context, refreshToken string) (*model.User, error) {
-	var user model.User
-
-	// Pass the context to the GORM query
-	if err := r.db.WithContext(ctx).Where("refresh_token = ?", refreshToken).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, pkg.NewNotFoundError("user with refresh token not found")
-		}
-		return nil, pkg.NewInternalServerError(err, "failed to get user by refresh token")
-	}
-
-	return &user, nil
-}
-
 func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 	// Pass the context to the GORM query
 	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
@@ -93,9 +109,20 @@ func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 	}
 	return nil
 }
+
+// Update saves every field of the user, provided the row still has the version the user
+// was loaded with, and increments the version. A concurrent change is a ConflictError.
 func (r *userRepository) Update(ctx context.Context, user *model.User) error {
+	expectedVersion := user.Version
+	user.Version++
+
 	// Pass the context to the GORM query
-	if err := r.db.WithContext(ctx).Save(user).Error; err != nil {
+	result := r.db.WithContext(ctx).Model(user).
+		Where("version = ?", expectedVersion).
+		Select("*").Omit(clause.Associations).
+		Updates(user)
+	if err := result.Error; err != nil {
+		user.Version = expectedVersion
 		// Check for duplicate key violations (unique constraints)
 		if strings.Contains(err.Error(), "duplicate key") ||
 			strings.Contains(err.Error(), "UNIQUE constraint failed") ||
@@ -108,6 +135,10 @@ func (r *userRepository) Update(ctx context.Context, user *model.User) error {
 		}
 		return pkg.NewInternalServerError(err, "failed to update user with ID %d", user.ID)
 	}
+	if result.RowsAffected == 0 {
+		user.Version = expectedVersion
+		return pkg.NewConflictError("version", "User with ID %d was changed by another request, reload it and try again", user.ID)
+	}
 	return nil
 }
 
@@ -118,3 +149,170 @@ func (r *userRepository) Delete(ctx context.Context, id uint) error {
 	}
 	return nil
 }
+
+// List returns one page of users matching the filter
+func (r *userRepository) List(ctx context.Context, filter UserFilter, page pkg.PageRequest) (*pkg.Page[model.User], error) {
+	query := r.db.Model(&model.User{})
+	if filter.IncludeDeleted || filter.OnlyDeleted {
+		query = query.Unscoped()
+	}
+	if filter.OnlyDeleted {
+		query = query.Where("deleted_at IS NOT NULL")
+	}
+	if filter.EmailPrefix != "" {
+		query = query.Where("email ILIKE ?", escapeLike(filter.EmailPrefix)+"%")
+	}
+	if filter.NameContains != "" {
+		query = query.Where("name ILIKE ?", "%"+escapeLike(filter.NameContains)+"%")
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+
+	return paginate(ctx, query, userSortColumns, defaultUserSort, page)
+}
+
+// Restore undoes the soft delete of a user. It fails with a DuplicateError when another
+// user has registered the email address in the meantime.
+func (r *userRepository) Restore(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND email NOT LIKE ?", id, erasedEmailPattern).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if err := result.Error; err != nil {
+		if strings.Contains(err.Error(), "duplicate key") && strings.Contains(err.Error(), "email") {
+			return pkg.NewDuplicateError("email", "The email of user %d is now used by another user", id)
+		}
+		return pkg.NewInternalServerError(err, "failed to restore user with ID %d", id)
+	}
+	if result.RowsAffected == 0 {
+		return pkg.NewNotFoundError("deleted user with ID %d not found", id)
+	}
+	return nil
+}
+
+// userOwnedModels hold rows that belong to a user and go when the user is purged.
+// Audit logs are kept.
+var userOwnedModels = []interface{}{
+	&model.RefreshToken{},
+	&model.Session{},
+	&model.PasswordResetToken{},
+	&model.EmailVerificationToken{},
+	&model.EmailChangeRequest{},
+	&model.RecoveryCode{},
+}
+
+// PurgeDeletedBefore permanently deletes up to limit users that were soft-deleted before
+// cutoff, together with their tokens, sessions and role assignments. Erased users are kept,
+// as Anonymize promises. It returns the purged users with their ID and avatar keys, so their
+// files can be removed as well.
+func (r *userRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time, limit int) ([]model.User, error) {
+	var purged []model.User
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Select("id", "avatar_key", "avatar_thumbnail_key").
+			Where("deleted_at IS NOT NULL AND deleted_at < ? AND email NOT LIKE ?", cutoff, erasedEmailPattern).
+			Order("deleted_at").Limit(limit).
+			Find(&purged).Error; err != nil {
+			return err
+		}
+		if len(purged) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(purged))
+		for i, user := range purged {
+			ids[i] = user.ID
+		}
+		if err := deleteOwnedRows(tx, ids); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.User{}, ids).Error
+	})
+	if err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to purge deleted users")
+	}
+	return purged, nil
+}
+
+// erasedEmailDomain replaces the address of erased users. Erased users are neither restored
+// nor purged; erasedEmailPattern matches their addresses.
+const (
+	erasedEmailDomain  = "erased.invalid"
+	erasedEmailPattern = "%@" + erasedEmailDomain
+)
+
+// Anonymize erases the personal data of a user in place: name, email, phone and credentials
+// are replaced, the user is marked deleted if it is not already, and every row owned by the
+// user is removed. The row itself stays so references such as audit entries remain valid.
+// It returns the user as it was before, so files such as the avatar can be removed too.
+func (r *userRepository) Anonymize(ctx context.Context, id uint) (*model.User, error) {
+	var previous model.User
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&previous, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return pkg.NewNotFoundError("user with ID %d not found", id)
+			}
+			return err
+		}
+
+		now := time.Now()
+		result := tx.Unscoped().Model(&model.User{}).Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"name":                 "Erased user",
+				"email":                fmt.Sprintf("erased-%d@%s", id, erasedEmailDomain),
+				"phone":                "",
+				"password":             "",
+				"email_verified_at":    nil,
+				"mfa_enabled_at":       nil,
+				"totp_secret":          "",
+				"totp_last_used_step":  0,
+				"avatar_key":           "",
+				"avatar_thumbnail_key": "",
+				"deleted_at":           gorm.Expr("COALESCE(deleted_at, ?)", now),
+				"version":              gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		return deleteOwnedRows(tx, []uint{id})
+	})
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return nil, err
+		}
+		return nil, pkg.NewInternalServerError(err, "failed to anonymize user with ID %d", id)
+	}
+	return &previous, nil
+}
+
+// SetAvatar stores the keys of the user's avatar files; empty keys remove the avatar
+func (r *userRepository) SetAvatar(ctx context.Context, id uint, key, thumbnailKey string) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"avatar_key":           key,
+			"avatar_thumbnail_key": thumbnailKey,
+			"version":              gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return pkg.NewInternalServerError(result.Error, "failed to set avatar of user with ID %d", id)
+	}
+	if result.RowsAffected == 0 {
+		return pkg.NewNotFoundError("user with ID %d not found", id)
+	}
+	return nil
+}
+
+// deleteOwnedRows deletes the tokens, sessions and role assignments of the users
+func deleteOwnedRows(tx *gorm.DB, ids []uint) error {
+	for _, owned := range userOwnedModels {
+		if err := tx.Unscoped().Where("user_id IN ?", ids).Delete(owned).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Exec("DELETE FROM user_roles WHERE user_id IN ?", ids).Error
+}
@@ -0,0 +1,900 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"your_project/internal/logger"
+	userlogger "your_project/internal/logger/user-logger"
+	"your_project/internal/mailer"
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+	"your_project/internal/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errRefreshTokenReused signals that a refresh token was exchanged concurrently
+var errRefreshTokenReused = errors.New("refresh token already used")
+
+// AuthSettings holds the configurable parts of the authentication flows
+type AuthSettings struct {
+	AppBaseURL       string        // Public base URL, used to build links in emails
+	PasswordResetTTL time.Duration // How long a password reset link stays valid
+
+	EmailVerificationTTL       time.Duration // How long an email verification link stays valid
+	VerificationResendCooldown time.Duration // Minimum time between two verification emails to the same user
+
+	EmailChangeTTL time.Duration // How long the confirmation link for a new email address stays valid
+}
+
+type AuthService interface {
+	IssueTokens(ctx context.Context, user *model.User, client ClientInfo) (*pkg.TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*pkg.TokenPair, error)
+	Logout(ctx context.Context, claims *pkg.JWTClaims) error
+	LogoutAll(ctx context.Context, userID uint) error
+	RevokeAccessTokens(ctx context.Context, userID uint) error
+	RevokeSessionAccessTokens(ctx context.Context, sessionID uint) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, claims *pkg.JWTClaims, currentPassword, newPassword string, client ClientInfo) (*pkg.TokenPair, error)
+	SendVerificationEmail(ctx context.Context, user *model.User) error
+	ResendVerificationEmail(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestEmailChange(ctx context.Context, user *model.User, newEmail string, actorID uint) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+}
+
+type authService struct {
+	users         repository.UserRepository
+	refreshTokens repository.RefreshTokenRepository
+	sessions      repository.SessionRepository
+	resetTokens   repository.PasswordResetTokenRepository
+	verifyTokens  repository.EmailVerificationTokenRepository
+	emailChanges  repository.EmailChangeRequestRepository
+	roles         repository.RoleRepository
+	auditLogs     repository.AuditLogRepository
+	jwtManager    *pkg.JWTManager
+	denylist      pkg.TokenDenylist
+	tokenHasher   *pkg.TokenHasher
+	throttle      LoginThrottle
+	hasher        pkg.PasswordHasher
+	policy        *pkg.PasswordPolicy
+	mailer        mailer.Mailer
+	settings      AuthSettings
+	db            *gorm.DB
+}
+
+func NewAuthService(
+	users repository.UserRepository,
+	refreshTokens repository.RefreshTokenRepository,
+	sessions repository.SessionRepository,
+	resetTokens repository.PasswordResetTokenRepository,
+	verifyTokens repository.EmailVerificationTokenRepository,
+	emailChanges repository.EmailChangeRequestRepository,
+	roles repository.RoleRepository,
+	auditLogs repository.AuditLogRepository,
+	jwtManager *pkg.JWTManager,
+	denylist pkg.TokenDenylist,
+	tokenHasher *pkg.TokenHasher,
+	throttle LoginThrottle,
+	hasher pkg.PasswordHasher,
+	policy *pkg.PasswordPolicy,
+	mail mailer.Mailer,
+	settings AuthSettings,
+	db *gorm.DB,
+) AuthService {
+	return &authService{
+		users:         users,
+		refreshTokens: refreshTokens,
+		sessions:      sessions,
+		resetTokens:   resetTokens,
+		verifyTokens:  verifyTokens,
+		emailChanges:  emailChanges,
+		roles:         roles,
+		auditLogs:     auditLogs,
+		jwtManager:    jwtManager,
+		denylist:      denylist,
+		tokenHasher:   tokenHasher,
+		throttle:      throttle,
+		hasher:        hasher,
+		policy:        policy,
+		mailer:        mail,
+		settings:      settings,
+		db:            db,
+	}
+}
+
+// IssueTokens starts a new session for a freshly authenticated user.
+// Each session gets its own refresh token family, so logins on other devices stay valid.
+func (s *authService) IssueTokens(ctx context.Context, user *model.User, client ClientInfo) (*pkg.TokenPair, error) {
+	var tokenPair *pkg.TokenPair
+
+	subject, err := s.tokenSubject(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		session := &model.Session{
+			UserID:     user.ID,
+			FamilyID:   uuid.New().String(),
+			DeviceName: client.DeviceName,
+			UserAgent:  client.UserAgent,
+			IPAddress:  client.IPAddress,
+			LastUsedAt: now,
+			ExpiresAt:  s.refreshExpiry(now),
+		}
+		if err := s.sessions.WithTx(tx).Create(ctx, session); err != nil {
+			return err
+		}
+
+		var err error
+		tokenPair, err = s.jwtManager.GenerateSessionTokenPair(subject, session.ID)
+		if err != nil {
+			return pkg.NewInternalServerError(err, "Failed to generate tokens")
+		}
+
+		return s.refreshTokens.WithTx(tx).Create(ctx, s.newRefreshToken(user.ID, session.FamilyID, tokenPair.RefreshToken))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userlogger.GetUserLogger(user.ID).Info("Started new session", "userID", user.ID, "device", client.DeviceName, "ip", client.IPAddress)
+	return tokenPair, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair in the same family.
+// Presenting a token that was already exchanged revokes the whole family.
+func (s *authService) RefreshTokens(ctx context.Context, refreshToken string) (*pkg.TokenPair, error) {
+	claims, err := s.jwtManager.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, pkg.NewUnauthorizedError("Invalid refresh token")
+	}
+	if claims.TokenType != pkg.TokenTypeRefresh {
+		return nil, pkg.NewUnauthorizedError("Invalid token type - refresh token required")
+	}
+
+	stored, err := s.refreshTokens.GetByTokenHash(ctx, s.tokenHasher.Hash(refreshToken))
+	if err != nil {
+		// Convert NotFoundError to UnauthorizedError for security
+		return nil, pkg.NewUnauthorizedError("Invalid or expired refresh token")
+	}
+
+	if stored.UsedAt != nil {
+		s.revokeReusedFamily(ctx, stored)
+		return nil, pkg.NewUnauthorizedError("Refresh token has already been used")
+	}
+	if stored.RevokedAt != nil {
+		return nil, pkg.NewUnauthorizedError("Session has been revoked")
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, pkg.NewUnauthorizedError("Refresh token has expired")
+	}
+
+	user, err := s.users.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, pkg.NewUnauthorizedError("Invalid or expired refresh token")
+	}
+
+	// Re-read roles so that role changes reach the client with the next refresh
+	subject, err := s.tokenSubject(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenPair, err := s.jwtManager.GenerateSessionTokenPair(subject, claims.SessionID)
+	if err != nil {
+		return nil, pkg.NewInternalServerError(err, "Failed to generate new tokens")
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		refreshTx := s.refreshTokens.WithTx(tx)
+
+		marked, err := refreshTx.MarkUsed(ctx, stored.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !marked {
+			// Another request exchanged this token first
+			return errRefreshTokenReused
+		}
+
+		if err := refreshTx.Create(ctx, s.newRefreshToken(user.ID, stored.FamilyID, tokenPair.RefreshToken)); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return s.sessions.WithTx(tx).TouchByFamily(ctx, stored.FamilyID, now, s.refreshExpiry(now))
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		s.revokeReusedFamily(ctx, stored)
+		return nil, pkg.NewUnauthorizedError("Refresh token has already been used")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenPair, nil
+}
+
+// Logout ends the session the access token belongs to and denies all of its access tokens
+func (s *authService) Logout(ctx context.Context, claims *pkg.JWTClaims) error {
+	logger := userlogger.GetUserLogger(claims.UserID)
+
+	if claims.SessionID != 0 {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			sessionsTx := s.sessions.WithTx(tx)
+
+			session, err := sessionsTx.GetByID(ctx, claims.SessionID)
+			if err != nil {
+				return err
+			}
+			if session.UserID != claims.UserID {
+				return pkg.NewNotFoundError("session with ID %d not found", claims.SessionID)
+			}
+			if err := sessionsTx.Revoke(ctx, session.ID); err != nil {
+				return err
+			}
+			return s.refreshTokens.WithTx(tx).RevokeFamily(ctx, session.FamilyID)
+		})
+		if err != nil {
+			logger.Errorw("Failed to revoke session during logout", "sessionID", claims.SessionID, "error", err)
+			return err
+		}
+	}
+
+	// Deny every access token of the session, not only the presented one. Tokens
+	// without a session are denied by their jti.
+	if claims.SessionID != 0 {
+		if err := s.denySessionAccessTokens(ctx, claims.SessionID); err != nil {
+			return err
+		}
+	} else {
+		expiresAt := time.Now().Add(time.Duration(s.jwtManager.ExpiryHours()) * time.Hour)
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+		if err := s.denylist.RevokeToken(ctx, claims.ID, expiresAt); err != nil {
+			return pkg.NewCacheError("denylist", claims.ID, err, "failed to revoke access token")
+		}
+	}
+
+	logger.Info("User logged out", "sessionID", claims.SessionID)
+	return nil
+}
+
+// LogoutAll ends every session of the user and denies all access tokens issued so far
+func (s *authService) LogoutAll(ctx context.Context, userID uint) error {
+	logger := userlogger.GetUserLogger(userID)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.revokeAllSessions(ctx, tx, userID)
+	})
+	if err != nil {
+		logger.Errorw("Failed to revoke sessions", "userID", userID, "error", err)
+		return err
+	}
+
+	if err := s.denyIssuedAccessTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	logger.Info("All sessions revoked", "userID", userID)
+	return nil
+}
+
+// RevokeAccessTokens denies the user's current access tokens without ending their sessions.
+// Clients get new tokens through the refresh endpoint, e.g. to pick up changed roles.
+func (s *authService) RevokeAccessTokens(ctx context.Context, userID uint) error {
+	return s.denyIssuedAccessTokens(ctx, userID)
+}
+
+// RevokeSessionAccessTokens denies the access tokens of a single session, e.g. once it has been revoked
+func (s *authService) RevokeSessionAccessTokens(ctx context.Context, sessionID uint) error {
+	return s.denySessionAccessTokens(ctx, sessionID)
+}
+
+// RequestPasswordReset emails a single-use reset link. It succeeds for unknown
+// emails too, so the endpoint cannot be used to find out who has an account.
+func (s *authService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			logger.APILog.Infow("Password reset requested for unknown email")
+			return nil
+		}
+		return err
+	}
+	logger := userlogger.GetUserLogger(user.ID)
+
+	token, err := pkg.GenerateOpaqueToken()
+	if err != nil {
+		return pkg.NewInternalServerError(err, "Failed to generate password reset token")
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		resetTx := s.resetTokens.WithTx(tx)
+
+		// Only the most recent link works
+		if err := resetTx.InvalidateForUser(ctx, user.ID); err != nil {
+			return err
+		}
+		return resetTx.Create(ctx, &model.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: s.tokenHasher.Hash(token),
+			ExpiresAt: time.Now().Add(s.settings.PasswordResetTTL),
+		})
+	})
+	if err != nil {
+		logger.Errorw("Failed to store password reset token", "userID", user.ID, "error", err)
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.settings.AppBaseURL, url.QueryEscape(token))
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not ask for a password reset, you can ignore this email.\n",
+			user.Name, s.settings.PasswordResetTTL, link),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		// Do not surface delivery failures, the response must not depend on the account existing
+		logger.Errorw("Failed to send password reset email", "userID", user.ID, "error", err)
+		return nil
+	}
+
+	logger.Info("Password reset email sent", "userID", user.ID)
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and signs the user out everywhere
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	invalidTokenErr := pkg.NewInvalidInputError("Invalid or expired password reset token")
+
+	stored, err := s.resetTokens.GetByTokenHash(ctx, s.tokenHasher.Hash(token))
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return invalidTokenErr
+		}
+		return err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return invalidTokenErr
+	}
+	logger := userlogger.GetUserLogger(stored.UserID)
+
+	owner, err := s.users.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Validate(newPassword, pkg.PasswordOwner{Name: owner.Name, Email: owner.Email}); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return pkg.NewInternalServerError(err, "Failed to hash password")
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		marked, err := s.resetTokens.WithTx(tx).MarkUsed(ctx, stored.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return invalidTokenErr
+		}
+
+		repoTx := s.users.WithTx(tx)
+		user, err := repoTx.GetByID(ctx, stored.UserID)
+		if err != nil {
+			return err
+		}
+		user.Password = hashedPassword
+		if err := repoTx.Update(ctx, user); err != nil {
+			return err
+		}
+
+		// Whoever knew the old password must not keep a session
+		return s.revokeAllSessions(ctx, tx, user.ID)
+	})
+	if err != nil {
+		logger.Errorw("Password reset failed", "userID", stored.UserID, "error", err)
+		return err
+	}
+
+	if err := s.denyIssuedAccessTokens(ctx, stored.UserID); err != nil {
+		return err
+	}
+
+	logger.Info("Password reset", "userID", stored.UserID)
+	return nil
+}
+
+// ChangePassword replaces the password of the authenticated user after checking the current one.
+// Every other session is signed out; the calling session continues with the returned token pair.
+func (s *authService) ChangePassword(ctx context.Context, claims *pkg.JWTClaims, currentPassword, newPassword string, client ClientInfo) (*pkg.TokenPair, error) {
+	logger := userlogger.GetUserLogger(claims.UserID)
+
+	user, err := s.users.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := confirmPassword(ctx, s.throttle, s.hasher, user, currentPassword); err != nil {
+		return nil, err
+	}
+	if currentPassword == newPassword {
+		return nil, pkg.NewValidationError("new_password", "***", "New password must be different from the current password")
+	}
+	if err := s.policy.Validate(newPassword, pkg.PasswordOwner{Name: user.Name, Email: user.Email}); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return nil, pkg.NewInternalServerError(err, "Failed to hash password")
+	}
+
+	var current *model.Session
+	if claims.SessionID != 0 {
+		current, err = s.sessions.GetByID(ctx, claims.SessionID)
+		if err != nil || current.UserID != user.ID || current.RevokedAt != nil {
+			return nil, pkg.NewUnauthorizedError("Session has been revoked")
+		}
+	}
+
+	subject, err := s.tokenSubject(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Access tokens issued before this point are denied once the new password is stored;
+	// the new pair is issued after it and stays valid
+	revokedAt := time.Now()
+
+	var tokenPair *pkg.TokenPair
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		user.Password = hashedPassword
+		if err := s.users.WithTx(tx).Update(ctx, user); err != nil {
+			return err
+		}
+
+		sessionsTx := s.sessions.WithTx(tx)
+		refreshTx := s.refreshTokens.WithTx(tx)
+		if err := refreshTx.RevokeAllForUser(ctx, user.ID); err != nil {
+			return err
+		}
+
+		if current == nil {
+			// Tokens without a session: sign out everywhere and start a fresh session
+			if err := sessionsTx.RevokeAllForUser(ctx, user.ID); err != nil {
+				return err
+			}
+			now := time.Now()
+			current = &model.Session{
+				UserID:     user.ID,
+				FamilyID:   uuid.New().String(),
+				DeviceName: client.DeviceName,
+				UserAgent:  client.UserAgent,
+				IPAddress:  client.IPAddress,
+				LastUsedAt: now,
+				ExpiresAt:  s.refreshExpiry(now),
+			}
+			if err := sessionsTx.Create(ctx, current); err != nil {
+				return err
+			}
+		} else if err := sessionsTx.RevokeAllForUserExcept(ctx, user.ID, current.ID); err != nil {
+			return err
+		}
+
+		var err error
+		tokenPair, err = s.jwtManager.GenerateSessionTokenPair(subject, current.ID)
+		if err != nil {
+			return pkg.NewInternalServerError(err, "Failed to generate tokens")
+		}
+		if err := refreshTx.Create(ctx, s.newRefreshToken(user.ID, current.FamilyID, tokenPair.RefreshToken)); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := sessionsTx.TouchByFamily(ctx, current.FamilyID, now, s.refreshExpiry(now)); err != nil {
+			return err
+		}
+
+		return s.auditLogs.WithTx(tx).Create(ctx, &model.AuditLog{
+			UserID:    user.ID,
+			ActorID:   user.ID,
+			Action:    model.AuditActionPasswordChanged,
+			IPAddress: client.IPAddress,
+			UserAgent: client.UserAgent,
+			Details:   "other sessions revoked",
+		})
+	})
+	if err != nil {
+		logger.Errorw("Password change failed", "userID", user.ID, "error", err)
+		return nil, err
+	}
+	if err := s.denyAccessTokensIssuedBefore(ctx, user.ID, revokedAt); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Password changed", "userID", user.ID, "sessionID", current.ID)
+	return tokenPair, nil
+}
+
+// SendVerificationEmail emails a link that proves the user owns their address.
+// Older links stop working once a new one is sent.
+func (s *authService) SendVerificationEmail(ctx context.Context, user *model.User) error {
+	logger := userlogger.GetUserLogger(user.ID)
+
+	token, err := pkg.GenerateOpaqueToken()
+	if err != nil {
+		return pkg.NewInternalServerError(err, "Failed to generate email verification token")
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		verifyTx := s.verifyTokens.WithTx(tx)
+
+		if err := verifyTx.InvalidateForUser(ctx, user.ID); err != nil {
+			return err
+		}
+		return verifyTx.Create(ctx, &model.EmailVerificationToken{
+			UserID:    user.ID,
+			TokenHash: s.tokenHasher.Hash(token),
+			ExpiresAt: time.Now().Add(s.settings.EmailVerificationTTL),
+		})
+	})
+	if err != nil {
+		logger.Errorw("Failed to store email verification token", "userID", user.ID, "error", err)
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/auth/verify-email?token=%s", s.settings.AppBaseURL, url.QueryEscape(token))
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n\nIf you did not create an account, you can ignore this email.\n",
+			user.Name, s.settings.EmailVerificationTTL, link),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		logger.Errorw("Failed to send verification email", "userID", user.ID, "error", err)
+		return pkg.NewServiceUnavailableError("mailer", err, "Failed to send verification email")
+	}
+
+	logger.Info("Verification email sent", "userID", user.ID)
+	return nil
+}
+
+// ResendVerificationEmail sends a new verification link, at most once per cooldown period.
+// Unknown and already verified addresses are accepted silently so accounts cannot be enumerated.
+func (s *authService) ResendVerificationEmail(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	latest, err := s.verifyTokens.GetLatestForUser(ctx, user.ID)
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if !errors.As(err, &notFoundErr) {
+			return err
+		}
+	} else if wait := time.Until(latest.CreatedAt.Add(s.settings.VerificationResendCooldown)); wait > 0 {
+		retryAfter := int(wait.Round(time.Second).Seconds())
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		return pkg.NewRateLimitError(retryAfter, "A verification email was sent recently, please try again in %d seconds", retryAfter)
+	}
+
+	return s.SendVerificationEmail(ctx, user)
+}
+
+// VerifyEmail marks the user's email as verified using the token from the verification email
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	invalidTokenErr := pkg.NewInvalidInputError("Invalid or expired email verification token")
+
+	stored, err := s.verifyTokens.GetByTokenHash(ctx, s.tokenHasher.Hash(token))
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return invalidTokenErr
+		}
+		return err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return invalidTokenErr
+	}
+	logger := userlogger.GetUserLogger(stored.UserID)
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		marked, err := s.verifyTokens.WithTx(tx).MarkUsed(ctx, stored.ID, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return invalidTokenErr
+		}
+
+		repoTx := s.users.WithTx(tx)
+		user, err := repoTx.GetByID(ctx, stored.UserID)
+		if err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		user.EmailVerifiedAt = &now
+		return repoTx.Update(ctx, user)
+	})
+	if err != nil {
+		logger.Errorw("Email verification failed", "userID", stored.UserID, "error", err)
+		return err
+	}
+
+	logger.Info("Email verified", "userID", stored.UserID)
+	return nil
+}
+
+// RequestEmailChange starts a change of the user's email address. The change is only applied once
+// the link sent to the new address is followed; the current address gets a notice in the meantime.
+func (s *authService) RequestEmailChange(ctx context.Context, user *model.User, newEmail string, actorID uint) error {
+	logger := userlogger.GetUserLogger(user.ID)
+
+	if strings.EqualFold(user.Email, newEmail) {
+		return pkg.NewValidationError("email", newEmail, "New email must be different from the current email")
+	}
+	if err := s.ensureEmailAvailable(ctx, user.ID, newEmail); err != nil {
+		return err
+	}
+
+	token, err := pkg.GenerateOpaqueToken()
+	if err != nil {
+		return pkg.NewInternalServerError(err, "Failed to generate email change token")
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		changeTx := s.emailChanges.WithTx(tx)
+
+		// Only the most recent request can be confirmed
+		if err := changeTx.InvalidateForUser(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := changeTx.Create(ctx, &model.EmailChangeRequest{
+			UserID:      user.ID,
+			NewEmail:    newEmail,
+			TokenHash:   s.tokenHasher.Hash(token),
+			RequestedBy: actorID,
+			ExpiresAt:   time.Now().Add(s.settings.EmailChangeTTL),
+		}); err != nil {
+			return err
+		}
+
+		return s.auditLogs.WithTx(tx).Create(ctx, &model.AuditLog{
+			UserID:  user.ID,
+			ActorID: actorID,
+			Action:  model.AuditActionEmailChangeRequested,
+			Details: fmt.Sprintf("new email %s", newEmail),
+		})
+	})
+	if err != nil {
+		logger.Errorw("Failed to store email change request", "userID", user.ID, "error", err)
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/auth/confirm-email-change?token=%s", s.settings.AppBaseURL, url.QueryEscape(token))
+	confirmation := mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to use this address for your account. It expires in %s and can only be used once.\n\n%s\n\nIf you did not ask for this change, you can ignore this email.\n",
+			user.Name, s.settings.EmailChangeTTL, link),
+	}
+	if err := s.mailer.Send(ctx, confirmation); err != nil {
+		logger.Errorw("Failed to send email change confirmation", "userID", user.ID, "error", err)
+		return pkg.NewServiceUnavailableError("mailer", err, "Failed to send email change confirmation")
+	}
+
+	notice := mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nA change of your account's email address to %s was requested. It takes effect once the new address is confirmed.\n\nIf you did not ask for this change, change your password and sign out of all sessions.\n",
+			user.Name, newEmail),
+	}
+	if err := s.mailer.Send(ctx, notice); err != nil {
+		// The change still needs the new address to be confirmed, so the request stands
+		logger.Errorw("Failed to send email change notice", "userID", user.ID, "error", err)
+	}
+
+	logger.Info("Email change requested", "userID", user.ID, "actorID", actorID)
+	return nil
+}
+
+// ConfirmEmailChange applies a pending email change using the token sent to the new address.
+// Access tokens carrying the old address are denied; refreshed tokens carry the new one.
+func (s *authService) ConfirmEmailChange(ctx context.Context, token string) error {
+	invalidTokenErr := pkg.NewInvalidInputError("Invalid or expired email change token")
+
+	stored, err := s.emailChanges.GetByTokenHash(ctx, s.tokenHasher.Hash(token))
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return invalidTokenErr
+		}
+		return err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return invalidTokenErr
+	}
+	logger := userlogger.GetUserLogger(stored.UserID)
+
+	// The address may have been registered since the change was requested
+	if err := s.ensureEmailAvailable(ctx, stored.UserID, stored.NewEmail); err != nil {
+		return err
+	}
+
+	var oldEmail string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		marked, err := s.emailChanges.WithTx(tx).MarkUsed(ctx, stored.ID, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return invalidTokenErr
+		}
+
+		repoTx := s.users.WithTx(tx)
+		user, err := repoTx.GetByID(ctx, stored.UserID)
+		if err != nil {
+			return err
+		}
+		oldEmail = user.Email
+		user.Email = stored.NewEmail
+		// Following the link proves ownership of the new address
+		user.EmailVerifiedAt = &now
+		if err := repoTx.Update(ctx, user); err != nil {
+			return err
+		}
+
+		return s.auditLogs.WithTx(tx).Create(ctx, &model.AuditLog{
+			UserID:  user.ID,
+			ActorID: stored.RequestedBy,
+			Action:  model.AuditActionEmailChanged,
+			Details: fmt.Sprintf("from %s to %s", oldEmail, stored.NewEmail),
+		})
+	})
+	if err != nil {
+		logger.Errorw("Email change failed", "userID", stored.UserID, "error", err)
+		return err
+	}
+
+	if err := s.denyIssuedAccessTokens(ctx, stored.UserID); err != nil {
+		return err
+	}
+
+	logger.Info("Email changed", "userID", stored.UserID)
+	return nil
+}
+
+// ensureEmailAvailable fails with a DuplicateError when another user already has the address
+func (s *authService) ensureEmailAvailable(ctx context.Context, userID uint, email string) error {
+	existing, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return nil
+		}
+		return err
+	}
+	if existing.ID != userID {
+		return pkg.NewDuplicateError("email", "Email %s is already taken by another user", email)
+	}
+	return nil
+}
+
+// revokeAllSessions revokes every session and refresh token of the user inside tx
+func (s *authService) revokeAllSessions(ctx context.Context, tx *gorm.DB, userID uint) error {
+	if err := s.sessions.WithTx(tx).RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.refreshTokens.WithTx(tx).RevokeAllForUser(ctx, userID)
+}
+
+// denyIssuedAccessTokens denies every access token issued to the user so far
+func (s *authService) denyIssuedAccessTokens(ctx context.Context, userID uint) error {
+	return s.denyAccessTokensIssuedBefore(ctx, userID, time.Now())
+}
+
+// denyAccessTokensIssuedBefore denies the user's access tokens issued before cutoff.
+// Access tokens live at most ExpiryHours, so the entry can be evicted after that.
+func (s *authService) denyAccessTokensIssuedBefore(ctx context.Context, userID uint, cutoff time.Time) error {
+	until := time.Now().Add(time.Duration(s.jwtManager.ExpiryHours()) * time.Hour)
+	if err := s.denylist.RevokeUserTokens(ctx, userID, cutoff, until); err != nil {
+		return pkg.NewCacheError("denylist", "user", err, "failed to revoke access tokens for user %d", userID)
+	}
+	return nil
+}
+
+// denySessionAccessTokens denies every access token issued for the session. A revoked
+// session never gets new tokens, so there is no cutoff; the entry lives as long as the tokens.
+func (s *authService) denySessionAccessTokens(ctx context.Context, sessionID uint) error {
+	until := time.Now().Add(time.Duration(s.jwtManager.ExpiryHours()) * time.Hour)
+	if err := s.denylist.RevokeSessionTokens(ctx, sessionID, until); err != nil {
+		return pkg.NewCacheError("denylist", "session", err, "failed to revoke access tokens for session %d", sessionID)
+	}
+	return nil
+}
+
+// tokenSubject collects the identity, roles and permissions that go into the user's access token
+func (s *authService) tokenSubject(ctx context.Context, user *model.User) (pkg.TokenSubject, error) {
+	roles, err := s.roles.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return pkg.TokenSubject{}, err
+	}
+
+	subject := pkg.TokenSubject{UserID: user.ID, Email: user.Email}
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		subject.Roles = append(subject.Roles, role.Name)
+		for _, permission := range role.Permissions {
+			if !seen[permission.Name] {
+				seen[permission.Name] = true
+				subject.Permissions = append(subject.Permissions, permission.Name)
+			}
+		}
+	}
+	sort.Strings(subject.Permissions)
+	return subject, nil
+}
+
+func (s *authService) newRefreshToken(userID uint, familyID, token string) *model.RefreshToken {
+	return &model.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: s.tokenHasher.Hash(token),
+		ExpiresAt: s.refreshExpiry(time.Now()),
+	}
+}
+
+func (s *authService) refreshExpiry(from time.Time) time.Time {
+	return from.Add(time.Duration(s.jwtManager.RefreshExpiryHours()) * time.Hour)
+}
+
+// revokeReusedFamily handles refresh token replay: the token may have been stolen,
+// so every token in its family is revoked and the user has to log in again.
+func (s *authService) revokeReusedFamily(ctx context.Context, stored *model.RefreshToken) {
+	logger.SystemLog.Warnw("Security event: refresh token reuse detected, revoking token family",
+		"user_id", stored.UserID,
+		"family_id", stored.FamilyID,
+		"token_id", stored.ID,
+	)
+	userlogger.GetUserLogger(stored.UserID).Warnw("Refresh token reuse detected, token family revoked", "familyID", stored.FamilyID)
+
+	if err := s.refreshTokens.RevokeFamily(ctx, stored.FamilyID); err != nil {
+		logger.SystemLog.Errorw("Failed to revoke refresh token family", "family_id", stored.FamilyID, "error", err)
+	}
+	if err := s.sessions.RevokeByFamily(ctx, stored.FamilyID); err != nil {
+		logger.SystemLog.Errorw("Failed to revoke session", "family_id", stored.FamilyID, "error", err)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+
+	"your_project/internal/logger"
+	userlogger "your_project/internal/logger/user-logger"
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+	"your_project/internal/repository"
+	"your_project/internal/storage"
+
+	"github.com/disintegration/imaging"
+	"github.com/google/uuid"
+	_ "golang.org/x/image/webp" // Registers the WebP decoder
+)
+
+// AvatarService manages user profile pictures. Uploads are decoded and re-encoded,
+// which drops EXIF and any other metadata, and a square thumbnail is generated.
+type AvatarService interface {
+	UploadAvatar(ctx context.Context, userID uint, r io.Reader) (*model.User, error)
+	GetAvatar(ctx context.Context, userID uint, thumbnail bool) (io.ReadCloser, *storage.Object, error)
+	DeleteAvatar(ctx context.Context, userID uint) error
+}
+
+// AvatarSettings holds the limits of avatar uploads
+type AvatarSettings struct {
+	MaxBytes int64 // Largest accepted upload
+}
+
+// AvatarContentTypes are the accepted upload formats, recognised from the content itself
+var AvatarContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+const (
+	avatarSize    = 512 // Longest side of the stored avatar
+	thumbnailSize = 128 // Side of the square thumbnail
+	// maxAvatarPixels rejects images that are small files but huge once decoded
+	maxAvatarPixels = 40_000_000
+)
+
+type avatarService struct {
+	users    repository.UserRepository
+	blob     storage.Blob
+	settings AvatarSettings
+}
+
+func NewAvatarService(users repository.UserRepository, blob storage.Blob, settings AvatarSettings) AvatarService {
+	return &avatarService{users: users, blob: blob, settings: settings}
+}
+
+// UploadAvatar replaces the user's avatar. JPEG uploads are stored as JPEG, other formats
+// as PNG so transparency is kept.
+func (s *avatarService) UploadAvatar(ctx context.Context, userID uint, r io.Reader) (*model.User, error) {
+	if err := authorizeUser(ctx, CanUpdate, "update", userID); err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.settings.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.settings.MaxBytes {
+		return nil, pkg.NewPayloadTooLargeError(s.settings.MaxBytes, int64(len(data)), "Avatar must not exceed %d bytes", s.settings.MaxBytes)
+	}
+
+	// The client's Content-Type is not trusted; the format is sniffed from the data
+	contentType := http.DetectContentType(data)
+	if !isAvatarContentType(contentType) {
+		return nil, pkg.NewUnsupportedMediaTypeError(contentType, AvatarContentTypes, "Avatar must be a JPEG, PNG, GIF or WebP image")
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, pkg.NewInvalidInputError("Avatar is not a valid image: %v", err)
+	}
+	if config.Width*config.Height > maxAvatarPixels {
+		return nil, pkg.NewValidationError("avatar", fmt.Sprintf("%dx%d", config.Width, config.Height), "Avatar must not exceed %d pixels", maxAvatarPixels)
+	}
+
+	// Apply the EXIF orientation before it is dropped by re-encoding
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, pkg.NewInvalidInputError("Avatar is not a valid image: %v", err)
+	}
+
+	format, extension, outputType := imaging.PNG, ".png", "image/png"
+	if contentType == "image/jpeg" {
+		format, extension, outputType = imaging.JPEG, ".jpg", "image/jpeg"
+	}
+	avatar, err := encodeImage(imaging.Fit(img, avatarSize, avatarSize, imaging.Lanczos), format)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := encodeImage(imaging.Fill(img, thumbnailSize, thumbnailSize, imaging.Center, imaging.Lanczos), format)
+	if err != nil {
+		return nil, err
+	}
+
+	// New keys on every upload, so cached copies of the old avatar are never served as the new one
+	base := fmt.Sprintf("avatars/%d/%s", userID, uuid.NewString())
+	key, thumbnailKey := base+extension, base+"_thumb"+extension
+	if err := s.blob.Put(ctx, key, bytes.NewReader(avatar), int64(len(avatar)), outputType); err != nil {
+		return nil, err
+	}
+	if err := s.blob.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), outputType); err != nil {
+		removeFiles(ctx, s.blob, []string{key})
+		return nil, err
+	}
+	if err := s.users.SetAvatar(ctx, userID, key, thumbnailKey); err != nil {
+		removeFiles(ctx, s.blob, []string{key, thumbnailKey})
+		return nil, err
+	}
+	removeFiles(ctx, s.blob, user.AvatarKeys())
+
+	userlogger.GetUserLogger(userID).Info("Avatar updated", "userID", userID)
+	return s.users.GetByID(ctx, userID)
+}
+
+// GetAvatar opens the user's avatar or its thumbnail. The caller closes the reader.
+func (s *avatarService) GetAvatar(ctx context.Context, userID uint, thumbnail bool) (io.ReadCloser, *storage.Object, error) {
+	if err := authorizeUser(ctx, CanView, "view", userID); err != nil {
+		return nil, nil, err
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := user.AvatarKey
+	if thumbnail {
+		key = user.AvatarThumbnailKey
+	}
+	if key == "" {
+		return nil, nil, pkg.NewNotFoundError("user %d has no avatar", userID)
+	}
+	return s.blob.Get(ctx, key)
+}
+
+// DeleteAvatar removes the user's avatar
+func (s *avatarService) DeleteAvatar(ctx context.Context, userID uint) error {
+	if err := authorizeUser(ctx, CanUpdate, "update", userID); err != nil {
+		return err
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.AvatarKey == "" {
+		return nil
+	}
+
+	if err := s.users.SetAvatar(ctx, userID, "", ""); err != nil {
+		return err
+	}
+	removeFiles(ctx, s.blob, user.AvatarKeys())
+
+	userlogger.GetUserLogger(userID).Info("Avatar removed", "userID", userID)
+	return nil
+}
+
+func isAvatarContentType(contentType string) bool {
+	for _, accepted := range AvatarContentTypes {
+		if contentType == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeImage(img image.Image, format imaging.Format) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, imaging.JPEGQuality(90)); err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to encode avatar")
+	}
+	return buf.Bytes(), nil
+}
+
+// removeFiles deletes stored files that are no longer referenced. A failure leaves an
+// orphaned file behind, which is logged but does not fail the caller.
+func removeFiles(ctx context.Context, blob storage.Blob, keys []string) {
+	for _, key := range keys {
+		if err := blob.Delete(ctx, key); err != nil {
+			logger.SystemLog.Errorw("Failed to delete stored file", "key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"your_project/internal/logger"
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+)
+
+// LockoutSettings holds the thresholds for failed login throttling. A zero threshold disables that check.
+type LockoutSettings struct {
+	MaxAccountFailures int           // Failures per account before it is locked
+	MaxIPFailures      int           // Failures per client IP before it is locked
+	FailureWindow      time.Duration // Failures older than this are forgotten
+	BaseLockout        time.Duration // First lockout; every further lockout doubles it
+	MaxLockout         time.Duration // Upper bound for the lockout duration, 0 for none
+}
+
+// LoginThrottle counts failed logins per account and per client IP and locks them out
+// temporarily, with exponential backoff for repeated lockouts
+type LoginThrottle interface {
+	// Check returns a RateLimitError while the account or IP is locked
+	Check(ctx context.Context, email, clientIP string) error
+	// RecordFailure counts a failed attempt. It returns a RateLimitError when this failure caused a lockout.
+	RecordFailure(ctx context.Context, email, clientIP string) error
+	// RecordSuccess clears the account's counters after a complete login
+	RecordSuccess(ctx context.Context, email string) error
+	// Unlock lifts an account lockout and resets its backoff
+	Unlock(ctx context.Context, email string) error
+}
+
+type loginThrottle struct {
+	store    pkg.LoginAttemptStore
+	settings LockoutSettings
+}
+
+func NewLoginThrottle(store pkg.LoginAttemptStore, settings LockoutSettings) LoginThrottle {
+	return &loginThrottle{store, settings}
+}
+
+func (t *loginThrottle) Check(ctx context.Context, email, clientIP string) error {
+	now := time.Now()
+	for _, key := range t.keys(email, clientIP) {
+		state, err := t.store.Get(ctx, key.name)
+		if err != nil {
+			return pkg.NewCacheError("login_attempts", key.name, err, "failed to read login attempts")
+		}
+		if now.Before(state.LockedUntil) {
+			return lockedError(state.LockedUntil.Sub(now))
+		}
+	}
+	return nil
+}
+
+func (t *loginThrottle) RecordFailure(ctx context.Context, email, clientIP string) error {
+	now := time.Now()
+	var lockErr error
+
+	for _, key := range t.keys(email, clientIP) {
+		locked := false
+		state, err := t.store.Update(ctx, key.name, func(state *pkg.AttemptState) {
+			if state.FirstFailure.IsZero() || now.Sub(state.FirstFailure) > t.settings.FailureWindow {
+				state.Failures = 0
+				state.FirstFailure = now
+			}
+			state.Failures++
+			if state.Failures >= key.maxFailures {
+				state.Lockouts++
+				state.LockedUntil = now.Add(t.lockoutDuration(state.Lockouts))
+				state.Failures = 0
+				state.FirstFailure = time.Time{}
+				locked = true
+			}
+		})
+		if err != nil {
+			return pkg.NewCacheError("login_attempts", key.name, err, "failed to record login attempt")
+		}
+
+		if locked {
+			logger.SystemLog.Warnw("Security event: login locked out after repeated failures",
+				"key", key.name,
+				"lockouts", state.Lockouts,
+				"locked_until", state.LockedUntil,
+			)
+			lockErr = lockedError(state.LockedUntil.Sub(now))
+		}
+	}
+	return lockErr
+}
+
+// RecordSuccess only clears the account key. The IP counter is left alone, so an attacker
+// holding one valid account cannot use it to reset the counter for their IP.
+func (t *loginThrottle) RecordSuccess(ctx context.Context, email string) error {
+	key := accountKey(email)
+	if err := t.store.Delete(ctx, key); err != nil {
+		return pkg.NewCacheError("login_attempts", key, err, "failed to reset login attempts")
+	}
+	return nil
+}
+
+func (t *loginThrottle) Unlock(ctx context.Context, email string) error {
+	key := accountKey(email)
+	if err := t.store.Delete(ctx, key); err != nil {
+		return pkg.NewCacheError("login_attempts", key, err, "failed to unlock account")
+	}
+	logger.SystemLog.Infow("Account unlocked", "key", key)
+	return nil
+}
+
+// confirmPassword checks the password a signed-in user enters to confirm a sensitive action.
+// Wrong passwords count towards the same account lockout as failed logins, so a stolen
+// session cannot be used to guess the password without limit.
+func confirmPassword(ctx context.Context, throttle LoginThrottle, hasher pkg.PasswordHasher, user *model.User, password string) error {
+	if err := throttle.Check(ctx, user.Email, ""); err != nil {
+		return err
+	}
+	if err := hasher.Verify(user.Password, password); err != nil {
+		if lockErr := throttle.RecordFailure(ctx, user.Email, ""); lockErr != nil {
+			return lockErr
+		}
+		return pkg.NewUnauthorizedError("Password is incorrect")
+	}
+	return nil
+}
+
+// confirmSecondFactor runs check on the authentication code a signed-in user entered.
+// Codes are guessable, so wrong ones count towards the account lockout like wrong passwords.
+func confirmSecondFactor(ctx context.Context, throttle LoginThrottle, user *model.User, check func() error) error {
+	if err := throttle.Check(ctx, user.Email, ""); err != nil {
+		return err
+	}
+	err := check()
+	var unauthorizedErr *pkg.UnauthorizedError
+	if errors.As(err, &unauthorizedErr) {
+		if lockErr := throttle.RecordFailure(ctx, user.Email, ""); lockErr != nil {
+			return lockErr
+		}
+	}
+	return err
+}
+
+// throttleKey is a store key and the failures it tolerates before locking
+type throttleKey struct {
+	name        string
+	maxFailures int
+}
+
+func (t *loginThrottle) keys(email, clientIP string) []throttleKey {
+	var keys []throttleKey
+	if t.settings.MaxAccountFailures > 0 && email != "" {
+		keys = append(keys, throttleKey{accountKey(email), t.settings.MaxAccountFailures})
+	}
+	if t.settings.MaxIPFailures > 0 && clientIP != "" {
+		keys = append(keys, throttleKey{"ip:" + clientIP, t.settings.MaxIPFailures})
+	}
+	return keys
+}
+
+// lockoutDuration doubles BaseLockout for every previous lockout, up to MaxLockout.
+// A MaxLockout of 0 leaves the backoff uncapped.
+func (t *loginThrottle) lockoutDuration(lockouts int) time.Duration {
+	capped := t.settings.MaxLockout > 0
+	duration := t.settings.BaseLockout
+	for i := 1; i < lockouts; i++ {
+		if (capped && duration >= t.settings.MaxLockout) || duration > math.MaxInt64/2 {
+			break
+		}
+		duration *= 2
+	}
+	if capped && duration > t.settings.MaxLockout {
+		duration = t.settings.MaxLockout
+	}
+	return duration
+}
+
+// accountKey keys accounts by normalized email, so unknown addresses are throttled
+// the same way and lockouts do not reveal which accounts exist
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func lockedError(wait time.Duration) error {
+	retryAfter := int(wait.Round(time.Second).Seconds())
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	return pkg.NewRateLimitError(retryAfter, "Too many failed login attempts, please try again in %d seconds", retryAfter)
+}
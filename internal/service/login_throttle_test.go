@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"your_project/internal/pkg"
+)
+
+func newTestThrottle(t *testing.T, settings LockoutSettings) *loginThrottle {
+	t.Helper()
+	store := pkg.NewMemoryAttemptStore(time.Hour, 24*time.Hour)
+	t.Cleanup(store.Close)
+	return NewLoginThrottle(store, settings).(*loginThrottle)
+}
+
+var testLockoutSettings = LockoutSettings{
+	MaxAccountFailures: 3,
+	MaxIPFailures:      5,
+	FailureWindow:      15 * time.Minute,
+	BaseLockout:        time.Minute,
+	MaxLockout:         time.Hour,
+}
+
+// fail records n failures and returns the error of the last one
+func fail(t *testing.T, throttle LoginThrottle, n int, email, clientIP string) error {
+	t.Helper()
+	var err error
+	for i := 0; i < n; i++ {
+		err = throttle.RecordFailure(context.Background(), email, clientIP)
+	}
+	return err
+}
+
+func assertLocked(t *testing.T, throttle LoginThrottle, email, clientIP string, want bool) {
+	t.Helper()
+	err := throttle.Check(context.Background(), email, clientIP)
+	var rateLimitErr *pkg.RateLimitError
+	if locked := errors.As(err, &rateLimitErr); locked != want {
+		t.Errorf("Check(%q, %q) = %v, want locked %t", email, clientIP, err, want)
+	}
+}
+
+func TestLoginThrottleLocksAccount(t *testing.T) {
+	throttle := newTestThrottle(t, testLockoutSettings)
+
+	if err := fail(t, throttle, 2, "ada@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("locked before the threshold: %v", err)
+	}
+	assertLocked(t, throttle, "ada@example.com", "", false)
+
+	err := fail(t, throttle, 1, "ada@example.com", "10.0.0.2")
+	var rateLimitErr *pkg.RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryTime != 60 {
+		t.Fatalf("third failure returned %v, want a 60 second lockout", err)
+	}
+
+	// The account is locked from every IP and whatever the case of the email
+	assertLocked(t, throttle, " Ada@Example.com", "10.0.0.3", true)
+	assertLocked(t, throttle, "grace@example.com", "10.0.0.1", false)
+}
+
+func TestLoginThrottleLocksIP(t *testing.T) {
+	throttle := newTestThrottle(t, testLockoutSettings)
+
+	// Spread over several accounts, so only the IP reaches its threshold
+	emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	for _, email := range emails {
+		_ = fail(t, throttle, 1, email, "10.0.0.1")
+	}
+
+	assertLocked(t, throttle, "f@example.com", "10.0.0.1", true)
+	assertLocked(t, throttle, "f@example.com", "10.0.0.2", false)
+	assertLocked(t, throttle, "a@example.com", "", false)
+}
+
+func TestLoginThrottleDisabledChecks(t *testing.T) {
+	throttle := newTestThrottle(t, LockoutSettings{FailureWindow: time.Minute, BaseLockout: time.Minute})
+
+	if err := fail(t, throttle, 50, "ada@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("thresholds of 0 locked: %v", err)
+	}
+	assertLocked(t, throttle, "ada@example.com", "10.0.0.1", false)
+}
+
+func TestLoginThrottleForgetsFailuresOutsideWindow(t *testing.T) {
+	throttle := newTestThrottle(t, testLockoutSettings)
+	ctx := context.Background()
+
+	_ = fail(t, throttle, 2, "ada@example.com", "")
+	// Move the window's start back past its end
+	_, _ = throttle.store.Update(ctx, accountKey("ada@example.com"), func(state *pkg.AttemptState) {
+		state.FirstFailure = state.FirstFailure.Add(-time.Hour)
+	})
+
+	if err := fail(t, throttle, 1, "ada@example.com", ""); err != nil {
+		t.Errorf("failures from an expired window counted: %v", err)
+	}
+}
+
+func TestLoginThrottleRecordSuccess(t *testing.T) {
+	throttle := newTestThrottle(t, testLockoutSettings)
+	ctx := context.Background()
+
+	_ = fail(t, throttle, 3, "ada@example.com", "")
+	_ = fail(t, throttle, 2, "grace@example.com", "10.0.0.1")
+	_ = fail(t, throttle, 2, "alan@example.com", "10.0.0.1")
+	if err := throttle.RecordSuccess(ctx, "ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := throttle.RecordSuccess(ctx, "grace@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertLocked(t, throttle, "ada@example.com", "", false)
+	state, _ := throttle.store.Get(ctx, accountKey("ada@example.com"))
+	if state != (pkg.AttemptState{}) {
+		t.Errorf("account state kept after a successful login: %+v", state)
+	}
+	// Only the account is reset; the IP keeps its count and locks on the fifth failure
+	if err := fail(t, throttle, 1, "edsger@example.com", "10.0.0.1"); err == nil {
+		t.Error("the IP counter was reset by a successful login")
+	}
+}
+
+func TestLoginThrottleBackoffDoubles(t *testing.T) {
+	throttle := newTestThrottle(t, testLockoutSettings)
+	ctx := context.Background()
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		_ = fail(t, throttle, 3, "ada@example.com", "")
+		state, _ := throttle.store.Get(ctx, accountKey("ada@example.com"))
+		if got := time.Until(state.LockedUntil).Round(time.Second); got != want {
+			t.Errorf("lockout %d lasts %v, want %v", state.Lockouts, got, want)
+		}
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxLockout time.Duration
+		lockouts   int
+		want       time.Duration
+	}{
+		{"first", time.Hour, 1, time.Minute},
+		{"second", time.Hour, 2, 2 * time.Minute},
+		{"fourth", time.Hour, 4, 8 * time.Minute},
+		{"capped", time.Hour, 10, time.Hour},
+		{"cap below base", 30 * time.Second, 1, 30 * time.Second},
+		{"no cap", 0, 10, 512 * time.Minute},
+		{"no cap does not overflow", 0, 1000, time.Minute << 27}, // The largest doubling that fits
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle := &loginThrottle{settings: LockoutSettings{BaseLockout: time.Minute, MaxLockout: tt.maxLockout}}
+			if got := throttle.lockoutDuration(tt.lockouts); got != tt.want {
+				t.Errorf("lockoutDuration(%d) = %v, want %v", tt.lockouts, got, tt.want)
+			}
+		})
+	}
+}
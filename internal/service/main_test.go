@@ -0,0 +1,17 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"your_project/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// TestMain discards the system and API logs, which logger.Init would write to files
+func TestMain(m *testing.M) {
+	logger.SystemLog = zap.NewNop().Sugar()
+	logger.APILog = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,451 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"strings"
+	"time"
+
+	"your_project/internal/logger"
+	userlogger "your_project/internal/logger/user-logger"
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+	"your_project/internal/repository"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	totpPeriod        = 30 // Seconds per TOTP time step
+	totpSkew          = 1  // Accept codes one step before or after the current one
+	totpQRCodeSize    = 256
+	recoveryCodeCount = 10
+)
+
+// recoveryCodeEncoding formats recovery codes without padding or easily confused characters
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFASettings holds the configurable parts of two-factor authentication
+type MFASettings struct {
+	Issuer string // Shown next to the account in authenticator apps
+}
+
+// TOTPEnrollment is what the user needs to add the account to an authenticator app
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCodePNG  string `json:"qr_code_png"` // Base64 encoded PNG of the otpauth URI
+}
+
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, userID uint, password string) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uint, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID uint, password, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, password, code string) ([]string, error)
+	IssuePendingToken(ctx context.Context, user *model.User) (string, error)
+	VerifyLogin(ctx context.Context, mfaToken, code, recoveryCode string, client ClientInfo) (*pkg.TokenPair, *model.User, error)
+}
+
+type mfaService struct {
+	users         repository.UserRepository
+	recoveryCodes repository.RecoveryCodeRepository
+	auth          AuthService
+	throttle      LoginThrottle
+	jwtManager    *pkg.JWTManager
+	denylist      pkg.TokenDenylist
+	tokenHasher   *pkg.TokenHasher
+	hasher        pkg.PasswordHasher
+	secretBox     *pkg.SecretBox
+	settings      MFASettings
+	db            *gorm.DB
+}
+
+func NewMFAService(
+	users repository.UserRepository,
+	recoveryCodes repository.RecoveryCodeRepository,
+	auth AuthService,
+	throttle LoginThrottle,
+	jwtManager *pkg.JWTManager,
+	denylist pkg.TokenDenylist,
+	tokenHasher *pkg.TokenHasher,
+	hasher pkg.PasswordHasher,
+	secretBox *pkg.SecretBox,
+	settings MFASettings,
+	db *gorm.DB,
+) MFAService {
+	return &mfaService{
+		users:         users,
+		recoveryCodes: recoveryCodes,
+		auth:          auth,
+		throttle:      throttle,
+		jwtManager:    jwtManager,
+		denylist:      denylist,
+		tokenHasher:   tokenHasher,
+		hasher:        hasher,
+		secretBox:     secretBox,
+		settings:      settings,
+		db:            db,
+	}
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. It only takes effect once
+// confirmed with a code, and enrolling again before that replaces the secret.
+func (s *mfaService) EnrollTOTP(ctx context.Context, userID uint, password string) (*TOTPEnrollment, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabledAt != nil {
+		return nil, pkg.NewConflictError("mfa_enabled", "Two-factor authentication is already enabled")
+	}
+	if err := confirmPassword(ctx, s.throttle, s.hasher, user, password); err != nil {
+		return nil, err
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.settings.Issuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, pkg.NewInternalServerError(err, "Failed to generate TOTP secret")
+	}
+
+	image, err := key.Image(totpQRCodeSize, totpQRCodeSize)
+	if err != nil {
+		return nil, pkg.NewInternalServerError(err, "Failed to render TOTP QR code")
+	}
+	var qrCode bytes.Buffer
+	if err := png.Encode(&qrCode, image); err != nil {
+		return nil, pkg.NewInternalServerError(err, "Failed to encode TOTP QR code")
+	}
+
+	sealed, err := s.secretBox.Seal(key.Secret())
+	if err != nil {
+		return nil, pkg.NewInternalServerError(err, "Failed to encrypt TOTP secret")
+	}
+	user.TOTPSecret = sealed
+	user.TOTPLastUsedStep = 0
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	userlogger.GetUserLogger(userID).Info("TOTP enrolment started", "userID", userID)
+	return &TOTPEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+		QRCodePNG:  base64.StdEncoding.EncodeToString(qrCode.Bytes()),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves the authenticator works.
+// It returns the recovery codes, which are only shown this once.
+func (s *mfaService) ConfirmTOTP(ctx context.Context, userID uint, code string) ([]string, error) {
+	logger := userlogger.GetUserLogger(userID)
+	var recoveryCodes []string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repoTx := s.users.WithTx(tx)
+		user, err := repoTx.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.MFAEnabledAt != nil {
+			return pkg.NewConflictError("mfa_enabled", "Two-factor authentication is already enabled")
+		}
+		if user.TOTPSecret == "" {
+			return pkg.NewInvalidInputError("No TOTP enrolment in progress")
+		}
+		if err := confirmSecondFactor(ctx, s.throttle, user, func() error { return s.checkTOTP(user, code) }); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		user.MFAEnabledAt = &now
+		if err := repoTx.Update(ctx, user); err != nil {
+			return err
+		}
+
+		recoveryCodes, err = s.replaceRecoveryCodes(ctx, tx, userID)
+		return err
+	})
+	if err != nil {
+		logger.Errorw("TOTP confirmation failed", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	logger.Info("Two-factor authentication enabled", "userID", userID)
+	return recoveryCodes, nil
+}
+
+// DisableTOTP turns two-factor authentication off. It needs both the password and a
+// current TOTP or recovery code, so a stolen session alone cannot remove the second factor.
+func (s *mfaService) DisableTOTP(ctx context.Context, userID uint, password, code string) error {
+	logger := userlogger.GetUserLogger(userID)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repoTx := s.users.WithTx(tx)
+		user, err := repoTx.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.MFAEnabledAt == nil {
+			return pkg.NewInvalidInputError("Two-factor authentication is not enabled")
+		}
+		if err := confirmPassword(ctx, s.throttle, s.hasher, user, password); err != nil {
+			return err
+		}
+		if err := confirmSecondFactor(ctx, s.throttle, user, func() error { return s.checkSecondFactor(ctx, tx, user, code) }); err != nil {
+			return err
+		}
+
+		user.MFAEnabledAt = nil
+		user.TOTPSecret = ""
+		user.TOTPLastUsedStep = 0
+		if err := repoTx.Update(ctx, user); err != nil {
+			return err
+		}
+		return s.recoveryCodes.WithTx(tx).DeleteForUser(ctx, userID)
+	})
+	if err != nil {
+		logger.Errorw("Disabling two-factor authentication failed", "userID", userID, "error", err)
+		return err
+	}
+
+	logger.Info("Two-factor authentication disabled", "userID", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking the password and a current TOTP code
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID uint, password, code string) ([]string, error) {
+	var recoveryCodes []string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repoTx := s.users.WithTx(tx)
+		user, err := repoTx.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.MFAEnabledAt == nil {
+			return pkg.NewInvalidInputError("Two-factor authentication is not enabled")
+		}
+		if err := confirmPassword(ctx, s.throttle, s.hasher, user, password); err != nil {
+			return err
+		}
+		if err := confirmSecondFactor(ctx, s.throttle, user, func() error { return s.checkTOTP(user, code) }); err != nil {
+			return err
+		}
+		if err := repoTx.Update(ctx, user); err != nil {
+			return err
+		}
+
+		recoveryCodes, err = s.replaceRecoveryCodes(ctx, tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userlogger.GetUserLogger(userID).Info("Recovery codes regenerated", "userID", userID)
+	return recoveryCodes, nil
+}
+
+// IssuePendingToken returns the short-lived token a user with two-factor authentication
+// gets after the password step of a login
+func (s *mfaService) IssuePendingToken(ctx context.Context, user *model.User) (string, error) {
+	token, err := s.jwtManager.GenerateMFAPendingToken(user.ID, user.Email)
+	if err != nil {
+		return "", pkg.NewInternalServerError(err, "Failed to generate MFA token")
+	}
+	return token, nil
+}
+
+// VerifyLogin completes a login by checking the second factor against the pending token.
+// Either a TOTP code or a recovery code must be given. The pending token can only be used once.
+func (s *mfaService) VerifyLogin(ctx context.Context, mfaToken, code, recoveryCode string, client ClientInfo) (*pkg.TokenPair, *model.User, error) {
+	claims, err := s.jwtManager.ValidateToken(mfaToken)
+	if err != nil || claims.TokenType != pkg.TokenTypeMFAPending {
+		return nil, nil, pkg.NewUnauthorizedError("Invalid or expired MFA token")
+	}
+	revoked, err := s.denylist.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, nil, pkg.NewCacheError("denylist", claims.ID, err, "failed to check MFA token")
+	}
+	if revoked {
+		return nil, nil, pkg.NewUnauthorizedError("Invalid or expired MFA token")
+	}
+	logger := userlogger.GetUserLogger(claims.UserID)
+
+	// Codes are guessable, so failures count towards the same lockout as passwords
+	if err := s.throttle.Check(ctx, claims.Email, client.IPAddress); err != nil {
+		return nil, nil, err
+	}
+
+	var user *model.User
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repoTx := s.users.WithTx(tx)
+		user, err = repoTx.GetByID(ctx, claims.UserID)
+		if err != nil {
+			return pkg.NewUnauthorizedError("Invalid or expired MFA token")
+		}
+		if user.MFAEnabledAt == nil {
+			return pkg.NewUnauthorizedError("Invalid or expired MFA token")
+		}
+
+		if recoveryCode != "" {
+			return s.consumeRecoveryCode(ctx, tx, user.ID, recoveryCode)
+		}
+		if err := s.checkTOTP(user, code); err != nil {
+			return err
+		}
+		return repoTx.Update(ctx, user)
+	})
+	if err != nil {
+		logger.Warnw("Second factor verification failed", "userID", claims.UserID, "error", err)
+		var unauthorizedErr *pkg.UnauthorizedError
+		if errors.As(err, &unauthorizedErr) {
+			if lockErr := s.throttle.RecordFailure(ctx, claims.Email, client.IPAddress); lockErr != nil {
+				return nil, nil, lockErr
+			}
+		}
+		return nil, nil, err
+	}
+	if err := s.throttle.RecordSuccess(ctx, claims.Email); err != nil {
+		logger.Errorw("Failed to reset login attempts", "userID", claims.UserID, "error", err)
+	}
+
+	if err := s.denylist.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, nil, pkg.NewCacheError("denylist", claims.ID, err, "failed to revoke MFA token")
+	}
+
+	tokenPair, err := s.auth.IssueTokens(ctx, user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	if recoveryCode != "" {
+		logger.Warnw("Logged in with a recovery code", "userID", user.ID)
+	}
+	return tokenPair, user, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code
+func (s *mfaService) checkSecondFactor(ctx context.Context, tx *gorm.DB, user *model.User, code string) error {
+	if err := s.checkTOTP(user, code); err == nil {
+		return nil
+	}
+	return s.consumeRecoveryCode(ctx, tx, user.ID, code)
+}
+
+// checkTOTP validates a TOTP code and records its time step on user so it cannot be replayed.
+// The caller persists user.
+func (s *mfaService) checkTOTP(user *model.User, code string) error {
+	invalidCodeErr := pkg.NewUnauthorizedError("Invalid authentication code")
+
+	code = strings.TrimSpace(code)
+	if code == "" || user.TOTPSecret == "" {
+		return invalidCodeErr
+	}
+
+	secret, err := s.secretBox.Open(user.TOTPSecret)
+	if err != nil {
+		logger.SystemLog.Errorw("Failed to decrypt TOTP secret", "user_id", user.ID, "error", err)
+		return pkg.NewInternalServerError(err, "Failed to verify authentication code")
+	}
+
+	opts := totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+	current := time.Now().Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= user.TOTPLastUsedStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), opts)
+		if err != nil {
+			return pkg.NewInternalServerError(err, "Failed to verify authentication code")
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			user.TOTPLastUsedStep = step
+			s.resealTOTPSecret(user)
+			return nil
+		}
+	}
+	return invalidCodeErr
+}
+
+// resealTOTPSecret encrypts the user's TOTP secret with the current key if it was sealed with
+// the previous one, so the previous key can be retired. The caller persists user.
+func (s *mfaService) resealTOTPSecret(user *model.User) {
+	resealed, changed, err := s.secretBox.Reseal(user.TOTPSecret)
+	if err != nil {
+		logger.SystemLog.Errorw("Failed to re-encrypt TOTP secret", "user_id", user.ID, "error", err)
+		return
+	}
+	if changed {
+		user.TOTPSecret = resealed
+	}
+}
+
+// consumeRecoveryCode marks a recovery code as used, failing if it is unknown or already used
+func (s *mfaService) consumeRecoveryCode(ctx context.Context, tx *gorm.DB, userID uint, code string) error {
+	consumed, err := s.recoveryCodes.WithTx(tx).Consume(ctx, userID, s.tokenHasher.Hash(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return pkg.NewUnauthorizedError("Invalid authentication code")
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a fresh set, returning them in plaintext
+func (s *mfaService) replaceRecoveryCodes(ctx context.Context, tx *gorm.DB, userID uint) ([]string, error) {
+	codesTx := s.recoveryCodes.WithTx(tx)
+	if err := codesTx.DeleteForUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	stored := make([]model.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, pkg.NewInternalServerError(err, "Failed to generate recovery codes")
+		}
+		codes = append(codes, code)
+		stored = append(stored, model.RecoveryCode{
+			UserID:   userID,
+			CodeHash: s.tokenHasher.Hash(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := codesTx.CreateBatch(ctx, stored); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code like "abcd-efgh-ijkl-mnop" (80 bits)
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+	return encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16], nil
+}
+
+// normalizeRecoveryCode makes recovery codes comparable regardless of case, dashes and spaces
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+)
+
+// Principal is the authenticated actor a service call is made on behalf of
+type Principal struct {
+	UserID      uint
+	Roles       []string
+	Permissions []string
+}
+
+// PrincipalFromClaims builds the principal described by a validated access token
+func PrincipalFromClaims(claims *pkg.JWTClaims) *Principal {
+	return &Principal{
+		UserID:      claims.UserID,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+	}
+}
+
+// HasPermission reports whether the principal holds the permission
+func (p *Principal) HasPermission(permission string) bool {
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUser reports whether the principal is the given user
+func (p *Principal) IsUser(userID uint) bool {
+	return p.UserID == userID
+}
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a context carrying the acting principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the acting principal, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// Policies decide whether an actor may act on a user. Users may always act on
+// their own account; acting on someone else's needs the matching permission.
+
+// CanView reports whether actor may read the target user
+func CanView(actor *Principal, targetUserID uint) bool {
+	return actor.IsUser(targetUserID) || actor.HasPermission(model.PermissionUsersRead)
+}
+
+// CanUpdate reports whether actor may change the target user
+func CanUpdate(actor *Principal, targetUserID uint) bool {
+	return actor.IsUser(targetUserID) || actor.HasPermission(model.PermissionUsersUpdate)
+}
+
+// CanDelete reports whether actor may delete the target user
+func CanDelete(actor *Principal, targetUserID uint) bool {
+	return actor.IsUser(targetUserID) || actor.HasPermission(model.PermissionUsersDelete)
+}
+
+// CanUnlock reports whether actor may lift a login lockout on the target user
+func CanUnlock(actor *Principal, targetUserID uint) bool {
+	return actor.HasPermission(model.PermissionUsersUnlock)
+}
+
+// CanList reports whether actor may list all users
+func CanList(actor *Principal) bool {
+	return actor.HasPermission(model.PermissionUsersRead)
+}
+
+// CanImport reports whether actor may create users in bulk
+func CanImport(actor *Principal) bool {
+	return actor.HasPermission(model.PermissionUsersImport)
+}
+
+// CanListDeleted reports whether actor may see deleted users in listings
+func CanListDeleted(actor *Principal) bool {
+	return actor.HasPermission(model.PermissionUsersReadDeleted)
+}
+
+// CanRestore reports whether actor may restore a deleted user
+func CanRestore(actor *Principal, targetUserID uint) bool {
+	return actor.HasPermission(model.PermissionUsersRestore)
+}
+
+// CanErase reports whether actor may erase the personal data of the target user
+func CanErase(actor *Principal, targetUserID uint) bool {
+	return actor.IsUser(targetUserID) || actor.HasPermission(model.PermissionUsersErase)
+}
+
+// authorizeUsers checks a policy that does not target a single user
+func authorizeUsers(ctx context.Context, policy func(*Principal) bool, action string) error {
+	actor, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return pkg.NewUnauthorizedError("User not authenticated")
+	}
+	if !policy(actor) {
+		return pkg.NewForbiddenError("user", action, "You are not allowed to %s users", action)
+	}
+	return nil
+}
+
+// authorizeUser checks a policy against the principal in ctx
+func authorizeUser(ctx context.Context, policy func(*Principal, uint) bool, action string, targetUserID uint) error {
+	actor, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return pkg.NewUnauthorizedError("User not authenticated")
+	}
+	if !policy(actor, targetUserID) {
+		return pkg.NewForbiddenError("user", action, "You are not allowed to %s user %d", action, targetUserID)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"your_project/internal/logger"
+	userlogger "your_project/internal/logger/user-logger"
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+	"your_project/internal/repository"
+	"your_project/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// PrivacyService serves the data subject requests of a user: a copy of everything stored
+// about them, and the erasure of their personal data.
+type PrivacyService interface {
+	ExportUserData(ctx context.Context, userID uint, client ClientInfo) (*UserDataExport, error)
+	EraseUser(ctx context.Context, id uint) error
+	EraseOwnAccount(ctx context.Context, id uint, password string) error
+}
+
+// UserDataExport is the personal data stored about a user. Password hashes, TOTP seeds and
+// token hashes are not part of it; the model types never render them.
+type UserDataExport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Profile     *model.User      `json:"profile"`
+	Roles       []string         `json:"roles"`
+	Sessions    []model.Session  `json:"sessions"`
+	AuditLog    []model.AuditLog `json:"audit_log"`
+	Log         string           `json:"log"` // Content of the user's log file
+	Avatar      []byte           `json:"-"`   // Avatar image, only part of the ZIP archive
+	AvatarName  string           `json:"-"`
+}
+
+// WriteZip writes the export as a ZIP archive with one file per section
+func (e *UserDataExport) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	sections := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", struct {
+			GeneratedAt time.Time   `json:"generated_at"`
+			Profile     *model.User `json:"profile"`
+			Roles       []string    `json:"roles"`
+		}{e.GeneratedAt, e.Profile, e.Roles}},
+		{"sessions.json", e.Sessions},
+		{"audit_log.json", e.AuditLog},
+	}
+	for _, section := range sections {
+		data, err := json.MarshalIndent(section.value, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(archive, section.name, e.GeneratedAt, data); err != nil {
+			return err
+		}
+	}
+	if err := writeZipFile(archive, "user.log", e.GeneratedAt, []byte(e.Log)); err != nil {
+		return err
+	}
+	if len(e.Avatar) > 0 {
+		if err := writeZipFile(archive, e.AvatarName, e.GeneratedAt, e.Avatar); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeZipFile(archive *zip.Writer, name string, modified time.Time, data []byte) error {
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	return err
+}
+
+type privacyService struct {
+	users     repository.UserRepository
+	sessions  repository.SessionRepository
+	roles     repository.RoleRepository
+	auditLogs repository.AuditLogRepository
+	auth      AuthService
+	throttle  LoginThrottle
+	hasher    pkg.PasswordHasher
+	blob      storage.Blob
+	db        *gorm.DB
+}
+
+func NewPrivacyService(
+	users repository.UserRepository,
+	sessions repository.SessionRepository,
+	roles repository.RoleRepository,
+	auditLogs repository.AuditLogRepository,
+	auth AuthService,
+	throttle LoginThrottle,
+	hasher pkg.PasswordHasher,
+	blob storage.Blob,
+	db *gorm.DB,
+) PrivacyService {
+	return &privacyService{
+		users:     users,
+		sessions:  sessions,
+		roles:     roles,
+		auditLogs: auditLogs,
+		auth:      auth,
+		throttle:  throttle,
+		hasher:    hasher,
+		blob:      blob,
+		db:        db,
+	}
+}
+
+// ExportUserData collects the profile, roles, sessions, audit trail, avatar and log file of the user.
+// The export itself is recorded in the audit trail.
+func (s *privacyService) ExportUserData(ctx context.Context, userID uint, client ClientInfo) (*UserDataExport, error) {
+	if err := authorizeUser(ctx, CanView, "export", userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.roles.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.auditLogs.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	userLog, err := userlogger.ReadUserLog(userID)
+	if err != nil {
+		return nil, pkg.NewInternalServerError(err, "failed to read log file of user %d", userID)
+	}
+
+	roleNames := make([]string, len(roles))
+	for i, role := range roles {
+		roleNames[i] = role.Name
+	}
+
+	export := &UserDataExport{
+		GeneratedAt: time.Now().UTC(),
+		Profile:     user,
+		Roles:       roleNames,
+		Sessions:    sessions,
+		AuditLog:    entries,
+		Log:         string(userLog),
+	}
+	if user.AvatarKey != "" {
+		file, _, err := s.blob.Get(ctx, user.AvatarKey)
+		if err != nil {
+			return nil, err
+		}
+		export.Avatar, err = io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, pkg.NewInternalServerError(err, "failed to read avatar of user %d", userID)
+		}
+		export.AvatarName = "avatar" + path.Ext(user.AvatarKey)
+	}
+
+	actorID := userID
+	if actor, ok := PrincipalFromContext(ctx); ok {
+		actorID = actor.UserID
+	}
+	if err := s.auditLogs.Create(ctx, &model.AuditLog{
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    model.AuditActionDataExported,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+	}); err != nil {
+		return nil, err
+	}
+
+	userlogger.GetUserLogger(userID).Info("Personal data exported", "userID", userID)
+	return export, nil
+}
+
+// EraseUser anonymises the user's personal data in place, removes their tokens, sessions,
+// role assignments, avatar and log file, and leaves a tombstone entry in the audit trail.
+// Erasure cannot be undone. The last administrator cannot be erased.
+func (s *privacyService) EraseUser(ctx context.Context, id uint) error {
+	if err := authorizeUser(ctx, CanErase, "erase", id); err != nil {
+		return err
+	}
+	actor, _ := PrincipalFromContext(ctx)
+
+	var previous *model.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		usersTx := s.users.WithTx(tx)
+		// A deleted user no longer counts as an administrator
+		_, err := usersTx.GetByID(ctx, id)
+		var notFoundErr *pkg.NotFoundError
+		switch {
+		case err == nil:
+			if err := ensureNotLastAdmin(ctx, s.roles.WithTx(tx), id); err != nil {
+				return err
+			}
+		case !errors.As(err, &notFoundErr):
+			return err
+		}
+
+		if previous, err = usersTx.Anonymize(ctx, id); err != nil {
+			return err
+		}
+		auditTx := s.auditLogs.WithTx(tx)
+		if err := auditTx.AnonymizeForUser(ctx, id); err != nil {
+			return err
+		}
+		return auditTx.Create(ctx, &model.AuditLog{
+			UserID:  id,
+			ActorID: actor.UserID,
+			Action:  model.AuditActionUserErased,
+		})
+	})
+	if err != nil {
+		logger.SystemLog.Errorw("Failed to erase user", "user_id", id, "error", err)
+		return err
+	}
+
+	removeFiles(ctx, s.blob, previous.AvatarKeys())
+	// The per-user log holds personal data too. Nothing may log to it afterwards,
+	// or a new file would be started.
+	if err := userlogger.RemoveUserLog(id); err != nil {
+		logger.SystemLog.Errorw("Failed to remove log file of erased user", "user_id", id, "error", err)
+	}
+	logger.SystemLog.Infow("User erased", "user_id", id, "actor_id", actor.UserID)
+
+	return s.auth.RevokeAccessTokens(ctx, id)
+}
+
+// EraseOwnAccount erases the user's own account after confirming their password
+func (s *privacyService) EraseOwnAccount(ctx context.Context, id uint, password string) error {
+	if err := authorizeUser(ctx, CanErase, "erase", id); err != nil {
+		return err
+	}
+
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := confirmPassword(ctx, s.throttle, s.hasher, user, password); err != nil {
+		return err
+	}
+
+	return s.EraseUser(ctx, id)
+}
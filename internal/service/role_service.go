@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"your_project/internal/logger"
+	userlogger "your_project/internal/logger/user-logger"
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+	"your_project/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type RoleService interface {
+	ListRoles(ctx context.Context) ([]model.Role, error)
+	GetUserRoles(ctx context.Context, userID uint) ([]model.Role, error)
+	AssignRole(ctx context.Context, userID uint, roleName string) error
+	RemoveRole(ctx context.Context, userID uint, roleName string) error
+	BootstrapAdmin(ctx context.Context, email string) error
+}
+
+type roleService struct {
+	roles repository.RoleRepository
+	users repository.UserRepository
+	auth  AuthService
+	db    *gorm.DB
+}
+
+func NewRoleService(roles repository.RoleRepository, users repository.UserRepository, auth AuthService, db *gorm.DB) RoleService {
+	return &roleService{roles, users, auth, db}
+}
+
+func (s *roleService) ListRoles(ctx context.Context) ([]model.Role, error) {
+	return s.roles.List(ctx)
+}
+
+func (s *roleService) GetUserRoles(ctx context.Context, userID uint) ([]model.Role, error) {
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.roles.GetUserRoles(ctx, userID)
+}
+
+// AssignRole gives the user a role. Their current access tokens are revoked so the
+// new permissions apply from the next refresh.
+func (s *roleService) AssignRole(ctx context.Context, userID uint, roleName string) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.users.WithTx(tx).GetByID(ctx, userID); err != nil {
+			return err
+		}
+		rolesTx := s.roles.WithTx(tx)
+		role, err := rolesTx.GetByName(ctx, roleName)
+		if err != nil {
+			return err
+		}
+		return rolesTx.AssignToUser(ctx, userID, role.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.SystemLog.Infow("Role assigned", "user_id", userID, "role", roleName)
+	userlogger.GetUserLogger(userID).Info("Role assigned", "role", roleName)
+	return s.auth.RevokeAccessTokens(ctx, userID)
+}
+
+// RemoveRole takes a role away from the user. The last administrator cannot be removed,
+// otherwise nobody could manage roles any more.
+func (s *roleService) RemoveRole(ctx context.Context, userID uint, roleName string) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.users.WithTx(tx).GetByID(ctx, userID); err != nil {
+			return err
+		}
+		rolesTx := s.roles.WithTx(tx)
+		role, err := rolesTx.GetByName(ctx, roleName)
+		if err != nil {
+			return err
+		}
+
+		if role.Name == model.RoleAdmin {
+			if err := ensureNotLastAdmin(ctx, rolesTx, userID); err != nil {
+				return err
+			}
+		}
+		return rolesTx.RemoveFromUser(ctx, userID, role.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.SystemLog.Infow("Role removed", "user_id", userID, "role", roleName)
+	userlogger.GetUserLogger(userID).Info("Role removed", "role", roleName)
+	return s.auth.RevokeAccessTokens(ctx, userID)
+}
+
+// ensureNotLastAdmin fails with a ConflictError when the user is the only administrator left,
+// so removing their admin role, deleting or erasing them would leave nobody to manage roles.
+// The user must not be deleted. Run it in the transaction that makes the change: it locks the
+// administrators' role assignments, so two administrators cannot remove each other at once.
+func ensureNotLastAdmin(ctx context.Context, roles repository.RoleRepository, userID uint) error {
+	userRoles, err := roles.GetUserRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, role := range userRoles {
+		if role.Name != model.RoleAdmin {
+			continue
+		}
+		admins, err := roles.CountUsersWithRole(ctx, role.ID)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return pkg.NewConflictError("last_admin", "Cannot remove the last administrator")
+		}
+	}
+	return nil
+}
+
+// BootstrapAdmin makes the user with the given email an administrator, so a fresh
+// installation has someone who can use the admin API. Unknown emails are only logged.
+func (s *roleService) BootstrapAdmin(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		var notFoundErr *pkg.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			logger.SystemLog.Warnw("Bootstrap admin not found, sign up and restart to grant the admin role", "email", email)
+			return nil
+		}
+		return err
+	}
+
+	role, err := s.roles.GetByName(ctx, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	return s.roles.AssignToUser(ctx, user.ID, role.ID)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+
+	userlogger "your_project/internal/logger/user-logger"
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+	"your_project/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ClientInfo describes the device a session is started from
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+type SessionService interface {
+	ListSessions(ctx context.Context, userID uint) ([]model.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint) error
+}
+
+type sessionService struct {
+	sessions      repository.SessionRepository
+	refreshTokens repository.RefreshTokenRepository
+	auth          AuthService
+	db            *gorm.DB
+}
+
+func NewSessionService(sessions repository.SessionRepository, refreshTokens repository.RefreshTokenRepository, auth AuthService, db *gorm.DB) SessionService {
+	return &sessionService{
+		sessions:      sessions,
+		refreshTokens: refreshTokens,
+		auth:          auth,
+		db:            db,
+	}
+}
+
+// ListSessions returns the user's active sessions
+func (s *sessionService) ListSessions(ctx context.Context, userID uint) ([]model.Session, error) {
+	return s.sessions.ListActiveByUser(ctx, userID)
+}
+
+// RevokeSession ends one of the user's sessions, revokes its refresh tokens and denies its access tokens
+func (s *sessionService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	logger := userlogger.GetUserLogger(userID)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sessionsTx := s.sessions.WithTx(tx)
+
+		session, err := sessionsTx.GetByID(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		// Do not reveal that another user's session exists
+		if session.UserID != userID {
+			return pkg.NewNotFoundError("session with ID %d not found", sessionID)
+		}
+
+		if err := sessionsTx.Revoke(ctx, session.ID); err != nil {
+			return err
+		}
+		return s.refreshTokens.WithTx(tx).RevokeFamily(ctx, session.FamilyID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.auth.RevokeSessionAccessTokens(ctx, sessionID); err != nil {
+		return err
+	}
+
+	logger.Info("Session revoked", "sessionID", sessionID)
+	return nil
+}
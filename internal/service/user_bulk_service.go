@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"your_project/internal/logger"
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+	"your_project/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// UserBulkService imports and exports users as CSV or NDJSON streams
+type UserBulkService interface {
+	ImportUsers(ctx context.Context, r io.Reader, format string, opts ImportOptions) (*ImportReport, error)
+	ExportUsers(ctx context.Context, w io.Writer, format string, filter repository.UserFilter) error
+}
+
+// DefaultImportBatchSize is how many rows are inserted per transaction
+const DefaultImportBatchSize = 500
+
+// ImportOptions controls a bulk import
+type ImportOptions struct {
+	DryRun    bool // Validate and insert every row, then roll back
+	BatchSize int  // Rows per transaction; DefaultImportBatchSize when 0
+}
+
+// Outcomes of an imported row
+const (
+	ImportRowCreated          = "created"
+	ImportRowSkippedDuplicate = "skipped_duplicate"
+	ImportRowInvalid          = "invalid"
+)
+
+// ImportRowResult is the outcome of one row. Rows are numbered from 1, not counting the CSV header.
+type ImportRowResult struct {
+	Row    int    `json:"row"`
+	Email  string `json:"email,omitempty"`
+	Status string `json:"status"`
+	UserID uint   `json:"user_id,omitempty"` // Not set in dry runs
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport summarises a bulk import
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"`
+	Invalid int               `json:"invalid"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+func (r *ImportReport) add(result ImportRowResult) {
+	switch result.Status {
+	case ImportRowCreated:
+		r.Created++
+	case ImportRowSkippedDuplicate:
+		r.Skipped++
+	case ImportRowInvalid:
+		r.Invalid++
+	}
+	r.Rows = append(r.Rows, result)
+}
+
+// importRow is one user of an import. Without a password the user has to reset it before logging in.
+type importRow struct {
+	Name     string `validate:"required"`
+	Email    string `validate:"required,email"`
+	Phone    string `validate:"required"`
+	Password string
+}
+
+// exportFields are the columns of an export. name, email and phone can be imported again.
+var exportFields = []string{"id", "name", "email", "phone", "email_verified_at", "mfa_enabled_at", "created_at", "updated_at", "deleted_at"}
+
+// errDryRun rolls back the transaction of a dry run batch
+var errDryRun = errors.New("dry run")
+
+type userBulkService struct {
+	repo     repository.UserRepository
+	hasher   pkg.PasswordHasher
+	policy   *pkg.PasswordPolicy
+	validate *validator.Validate
+	db       *gorm.DB
+}
+
+func NewUserBulkService(repo repository.UserRepository, hasher pkg.PasswordHasher, policy *pkg.PasswordPolicy, db *gorm.DB) UserBulkService {
+	return &userBulkService{
+		repo:     repo,
+		hasher:   hasher,
+		policy:   policy,
+		validate: validator.New(),
+		db:       db,
+	}
+}
+
+// ImportUsers creates a user for every valid row of the stream. Rows are validated one by one
+// and inserted in batched transactions; a row whose email is taken, in the database or by an
+// earlier row, is skipped. Imported users are not verified and get no verification email.
+func (s *userBulkService) ImportUsers(ctx context.Context, r io.Reader, format string, opts ImportOptions) (*ImportReport, error) {
+	if err := authorizeUsers(ctx, CanImport, "import"); err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultImportBatchSize
+	}
+
+	records, err := pkg.NewRecordReader(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun, Rows: []ImportRowResult{}}
+	seen := make(map[string]int) // Lower-cased email to the row that used it first, as the unique index compares them
+	batch := make([]importBatchRow, 0, opts.BatchSize)
+
+	for row := 1; ; row++ {
+		record, err := records.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var invalidErr *pkg.InvalidInputError
+			if !errors.As(err, &invalidErr) {
+				// The source itself failed, e.g. the request body exceeded its limit
+				return nil, err
+			}
+			report.add(ImportRowResult{Row: row, Status: ImportRowInvalid, Error: err.Error()})
+			continue
+		}
+
+		user, err := s.prepareRow(record)
+		if err != nil {
+			report.add(ImportRowResult{Row: row, Email: record["email"], Status: ImportRowInvalid, Error: err.Error()})
+			continue
+		}
+		key := strings.ToLower(user.Email)
+		if first, ok := seen[key]; ok {
+			report.add(ImportRowResult{Row: row, Email: user.Email, Status: ImportRowSkippedDuplicate, Error: fmt.Sprintf("Email already used in row %d", first)})
+			continue
+		}
+		seen[key] = row
+
+		batch = append(batch, importBatchRow{row: row, user: user})
+		if len(batch) == opts.BatchSize {
+			if err := s.insertBatch(ctx, batch, opts.DryRun, report); err != nil {
+				return nil, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.insertBatch(ctx, batch, opts.DryRun, report); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.SystemLog.Infow("Users imported", "dry_run", opts.DryRun, "created", report.Created, "skipped", report.Skipped, "invalid", report.Invalid)
+	return report, nil
+}
+
+type importBatchRow struct {
+	row  int
+	user *model.User
+}
+
+// prepareRow validates a record and returns the user to create, with the password hashed
+func (s *userBulkService) prepareRow(record map[string]string) (*model.User, error) {
+	row := importRow{
+		Name:     strings.TrimSpace(record["name"]),
+		Email:    strings.TrimSpace(record["email"]),
+		Phone:    strings.TrimSpace(record["phone"]),
+		Password: record["password"],
+	}
+	if err := s.validate.Struct(row); err != nil {
+		return nil, err
+	}
+
+	user := &model.User{Name: row.Name, Email: row.Email, Phone: row.Phone}
+	if row.Password != "" {
+		if err := s.policy.Validate(row.Password, pkg.PasswordOwner{Name: row.Name, Email: row.Email}); err != nil {
+			return nil, err
+		}
+		hashed, err := s.hasher.Hash(row.Password)
+		if err != nil {
+			return nil, pkg.NewInternalServerError(err, "Failed to hash password")
+		}
+		user.Password = hashed
+	}
+	return user, nil
+}
+
+// insertBatch creates the users of a batch in one transaction. Each row runs in a savepoint,
+// so a duplicate email skips the row without aborting the batch.
+func (s *userBulkService) insertBatch(ctx context.Context, batch []importBatchRow, dryRun bool, report *ImportReport) error {
+	results := make([]ImportRowResult, 0, len(batch))
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, item := range batch {
+			result := ImportRowResult{Row: item.row, Email: item.user.Email, Status: ImportRowCreated}
+			err := tx.Transaction(func(rowTx *gorm.DB) error {
+				return s.repo.WithTx(rowTx).Create(ctx, item.user)
+			})
+			var duplicateErr *pkg.DuplicateError
+			switch {
+			case errors.As(err, &duplicateErr):
+				result.Status = ImportRowSkippedDuplicate
+				result.Error = err.Error()
+			case err != nil:
+				return err
+			case !dryRun:
+				result.UserID = item.user.ID
+			}
+			results = append(results, result)
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return err
+	}
+
+	for _, result := range results {
+		report.add(result)
+	}
+	return nil
+}
+
+// ExportUsers writes the users matching the filter to w, oldest first. Users are read
+// page by page, so the export does not hold every user in memory.
+func (s *userBulkService) ExportUsers(ctx context.Context, w io.Writer, format string, filter repository.UserFilter) error {
+	if err := authorizeUsers(ctx, CanList, "export"); err != nil {
+		return err
+	}
+	if filter.IncludeDeleted || filter.OnlyDeleted {
+		if err := authorizeUsers(ctx, CanListDeleted, "export deleted"); err != nil {
+			return err
+		}
+	}
+
+	records, err := pkg.NewRecordWriter(format, w, exportFields)
+	if err != nil {
+		return err
+	}
+
+	page := pkg.PageRequest{Limit: pkg.MaxPageLimit, Sort: []pkg.SortField{{Field: "id"}}}
+	for {
+		users, err := s.repo.List(ctx, filter, page)
+		if err != nil {
+			return err
+		}
+		for i := range users.Items {
+			user := &users.Items[i]
+			var deletedAt *time.Time
+			if user.DeletedAt.Valid {
+				deletedAt = &user.DeletedAt.Time
+			}
+			if err := records.Write([]interface{}{
+				user.ID, user.Name, user.Email, user.Phone, user.EmailVerifiedAt, user.MFAEnabledAt, user.CreatedAt, user.UpdatedAt, deletedAt,
+			}); err != nil {
+				return err
+			}
+		}
+		if !users.HasMore {
+			break
+		}
+		page.Cursor = users.NextCursor
+	}
+
+	return records.Flush()
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"your_project/internal/model"
+	"your_project/internal/pkg"
+)
+
+// newTestBulkService builds a bulk service without a database, enough for rows that never reach it
+func newTestBulkService(t *testing.T) *userBulkService {
+	t.Helper()
+	hasher, err := pkg.NewPasswordHasher(pkg.PasswordAlgorithmArgon2id, pkg.Argon2Params{Memory: 1024, Iterations: 1, Threads: 1}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &pkg.PasswordPolicy{MinLength: 10, RequireDigit: true, DisallowPersonalInfo: true}
+	return NewUserBulkService(nil, hasher, policy, nil).(*userBulkService)
+}
+
+func importContext() context.Context {
+	return WithPrincipal(context.Background(), &Principal{UserID: 1, Permissions: []string{model.PermissionUsersImport}})
+}
+
+func TestPrepareRow(t *testing.T) {
+	s := newTestBulkService(t)
+
+	user, err := s.prepareRow(map[string]string{"name": " Ada ", "email": "ada@example.com", "phone": "123", "password": "analytical1engine"})
+	if err != nil {
+		t.Fatalf("valid row rejected: %v", err)
+	}
+	if user.Name != "Ada" || user.Email != "ada@example.com" || user.Phone != "123" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if err := s.hasher.Verify(user.Password, "analytical1engine"); err != nil {
+		t.Errorf("password was not hashed: %v", err)
+	}
+
+	// Without a password the user has to reset it before logging in
+	user, err = s.prepareRow(map[string]string{"name": "Grace", "email": "grace@example.com", "phone": "456"})
+	if err != nil {
+		t.Fatalf("row without password rejected: %v", err)
+	}
+	if user.Password != "" {
+		t.Errorf("password = %q, want empty", user.Password)
+	}
+
+	invalid := []map[string]string{
+		{"email": "ada@example.com", "phone": "123"},
+		{"name": "Ada", "email": "not an email", "phone": "123"},
+		{"name": "Ada", "email": "ada@example.com"},
+		{"name": "Ada", "email": "ada@example.com", "phone": "123", "password": "short1"},
+		{"name": "Ada", "email": "ada@example.com", "phone": "123", "password": "ada@example.com1"},
+	}
+	for _, record := range invalid {
+		if _, err := s.prepareRow(record); err == nil {
+			t.Errorf("invalid row %v accepted", record)
+		}
+	}
+}
+
+func TestImportUsersReportsInvalidRows(t *testing.T) {
+	s := newTestBulkService(t)
+	input := "name,email,phone\n,ada@example.com,123\nGrace,grace@example.com\nBad,row\n"
+
+	report, err := s.ImportUsers(importContext(), strings.NewReader(input), pkg.RecordFormatCSV, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Invalid != 3 || report.Created != 0 || report.Skipped != 0 {
+		t.Errorf("report = %+v, want 3 invalid rows", report)
+	}
+	for i, result := range report.Rows {
+		if result.Row != i+1 || result.Status != ImportRowInvalid || result.Error == "" {
+			t.Errorf("row result %d = %+v", i, result)
+		}
+	}
+}
+
+func TestImportUsersNeedsPermission(t *testing.T) {
+	s := newTestBulkService(t)
+	ctx := WithPrincipal(context.Background(), &Principal{UserID: 1})
+
+	var forbiddenErr *pkg.ForbiddenError
+	if _, err := s.ImportUsers(ctx, strings.NewReader("name,email,phone\n"), pkg.RecordFormatCSV, ImportOptions{}); !errors.As(err, &forbiddenErr) {
+		t.Errorf("ImportUsers returned %v, want a ForbiddenError", err)
+	}
+}
@@ -2,7 +2,11 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"net/mail"
+	"strings"
 	"time"
 
 	"your_project/internal/logger"
@@ -10,32 +14,57 @@ import (
 	"your_project/internal/model"
 	"your_project/internal/pkg"
 	"your_project/internal/repository"
+	"your_project/internal/storage"
 
 	"gorm.io/gorm"
 )
 
 type UserService interface {
 	GetUser(ctx context.Context, id uint) (*model.User, error)
+	ListUsers(ctx context.Context, filter repository.UserFilter, page pkg.PageRequest) (*pkg.Page[model.User], error)
 	CreateUser(ctx context.Context, user *model.User) error
 	UpdateUser(ctx context.Context, user *model.User) error
-	DeleteUser(ctx context.Context, id uint) error
+	PatchUser(ctx context.Context, id, version uint, patch pkg.Patch) (*model.User, error)
+	DeleteUser(ctx context.Context, id, version uint) error
+	DeleteOwnAccount(ctx context.Context, id uint, password string) error
+	RestoreUser(ctx context.Context, id uint) (*model.User, error)
+	PurgeDeletedUsers(ctx context.Context) (int64, error)
 	RegisterUser(ctx context.Context, user *model.User) error
-	LoginUser(ctx context.Context, email, password string) (*model.User, error)
+	LoginUser(ctx context.Context, email, password, clientIP string) (*model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
-	RefreshToken(ctx context.Context, refreshToken string) (*model.User, error)
-	UpdateRefreshToken(ctx context.Context, userID uint, refreshToken string, expiry time.Time) error
+	UnlockUser(ctx context.Context, id uint) error
 }
 
+// UserSettings holds the configurable parts of the user flows
+type UserSettings struct {
+	RequireVerifiedEmail bool          // Refuse logins until the email address has been verified
+	DeletedUserRetention time.Duration // How long deleted users can be restored before they are purged; 0 keeps them
+}
+
+// purgeBatchSize limits how many users are purged in one transaction
+const purgeBatchSize = 100
+
 type userService struct {
-	repo repository.UserRepository
-	db   *gorm.DB
+	repo     repository.UserRepository
+	roles    repository.RoleRepository
+	auth     AuthService
+	throttle LoginThrottle
+	hasher   pkg.PasswordHasher
+	policy   *pkg.PasswordPolicy
+	blob     storage.Blob
+	settings UserSettings
+	db       *gorm.DB
 }
 
-func NewUserService(repo repository.UserRepository, db *gorm.DB) UserService {
-	return &userService{repo, db}
+func NewUserService(repo repository.UserRepository, roles repository.RoleRepository, auth AuthService, throttle LoginThrottle, hasher pkg.PasswordHasher, policy *pkg.PasswordPolicy, blob storage.Blob, settings UserSettings, db *gorm.DB) UserService {
+	return &userService{repo, roles, auth, throttle, hasher, policy, blob, settings, db}
 }
 
 func (s *userService) GetUser(ctx context.Context, id uint) (*model.User, error) {
+	if err := authorizeUser(ctx, CanView, "view", id); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		// Propagate repository errors, which are already custom errors
@@ -44,11 +73,29 @@ func (s *userService) GetUser(ctx context.Context, id uint) (*model.User, error)
 	return user, nil
 }
 
+// ListUsers returns one page of users. Deleted users are only included for actors allowed to see them.
+func (s *userService) ListUsers(ctx context.Context, filter repository.UserFilter, page pkg.PageRequest) (*pkg.Page[model.User], error) {
+	if err := authorizeUsers(ctx, CanList, "list"); err != nil {
+		return nil, err
+	}
+	if filter.IncludeDeleted || filter.OnlyDeleted {
+		if err := authorizeUsers(ctx, CanListDeleted, "list deleted"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.List(ctx, filter, page)
+}
+
+// CreateUser stores a new user. The password is checked against the password policy and hashed.
 func (s *userService) CreateUser(ctx context.Context, user *model.User) error {
 	logger := logger.APILog
 	logger.Info("Creating user", "user", user.Name)
 	// Add any business logic validation here and return pkg.NewInvalidInputError if needed
 
+	if err := s.prepareNewUser(user); err != nil {
+		return err
+	}
 	if err := s.repo.Create(ctx, user); err != nil {
 		// Propagate repository errors
 		return err
@@ -56,22 +103,79 @@ func (s *userService) CreateUser(ctx context.Context, user *model.User) error {
 	return nil
 }
 
+// UpdateUser stores the changed profile fields. The email address is not changed directly:
+// a different address starts an email change that the new address has to confirm, and
+// the returned user carries it in PendingEmail. A non-zero user.Version must match the stored version.
 func (s *userService) UpdateUser(ctx context.Context, user *model.User) error {
+	updated, err := s.updateUser(ctx, user.ID, user.Version, func(stored *model.User) (string, error) {
+		stored.Name = user.Name
+		return user.Email, nil
+	})
+	if err != nil {
+		return err
+	}
+	*user = *updated
+	return nil
+}
+
+// PatchUser applies a JSON merge patch or JSON patch to the editable fields of the user
+// (name, email and phone). Email changes need confirmation, as in UpdateUser.
+// A non-zero version must match the stored version.
+func (s *userService) PatchUser(ctx context.Context, id, version uint, patch pkg.Patch) (*model.User, error) {
+	return s.updateUser(ctx, id, version, func(stored *model.User) (string, error) {
+		document, err := json.Marshal(newUserDocument(stored))
+		if err != nil {
+			return "", pkg.NewInternalServerError(err, "failed to encode user %d", stored.ID)
+		}
+		patched, err := patch.Apply(document)
+		if err != nil {
+			return "", err
+		}
+
+		var result userDocument
+		decoder := json.NewDecoder(bytes.NewReader(patched))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&result); err != nil {
+			return "", pkg.NewInvalidInputError("Patch produces an invalid user: %v", err)
+		}
+		if err := result.validate(); err != nil {
+			return "", err
+		}
+
+		stored.Name = result.Name
+		stored.Phone = result.Phone
+		return result.Email, nil
+	})
+}
+
+// updateUser runs apply on the stored user inside a transaction and saves the result.
+// apply returns the email the user should have, which starts an email change when it differs.
+// A non-zero version is compared with the stored one first (If-Match).
+func (s *userService) updateUser(ctx context.Context, id, version uint, apply func(stored *model.User) (string, error)) (*model.User, error) {
 	// Add any business logic validation here and return pkg.NewInvalidInputError if needed
-	logger := userlogger.GetUserLogger(user.ID)
+	if err := authorizeUser(ctx, CanUpdate, "update", id); err != nil {
+		return nil, err
+	}
+	logger := userlogger.GetUserLogger(id)
 
+	var stored *model.User
+	var newEmail string
 	// Pass the context to the transaction
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		repoTx := s.repo.WithTx(tx)
 		// Pass the context to repository calls within the transaction
-		oldUser, err := repoTx.GetByID(ctx, user.ID)
+		oldUser, err := repoTx.GetByID(ctx, id)
 		if err != nil {
 			// Propagate repository errors (e.g., NotFoundError)
-			logger.Errorw("User not found during update transaction", "userID", user.ID, "error", err)
+			logger.Errorw("User not found during update transaction", "userID", id, "error", err)
+			return err
+		}
+		if err := checkVersion(oldUser, version); err != nil {
+			return err
+		}
+		if newEmail, err = apply(oldUser); err != nil {
 			return err
 		}
-		oldUser.Email = user.Email
-		oldUser.Name = user.Name
 		// Pass the context to repository calls within the transaction
 		if err := repoTx.Update(ctx, oldUser); err != nil {
 			// Propagate repository errors
@@ -79,20 +183,121 @@ func (s *userService) UpdateUser(ctx context.Context, user *model.User) error {
 			return err
 		}
 		logger.Info("User updated", "user", oldUser.ID)
+		stored = oldUser
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if newEmail != "" && !strings.EqualFold(newEmail, stored.Email) {
+		var actorID uint
+		if principal, ok := PrincipalFromContext(ctx); ok {
+			actorID = principal.UserID
+		}
+		if err := s.auth.RequestEmailChange(ctx, stored, newEmail, actorID); err != nil {
+			return nil, err
+		}
+		stored.PendingEmail = newEmail
+	}
+	return stored, nil
 }
 
-func (s *userService) DeleteUser(ctx context.Context, id uint) error {
+// DeleteUser soft-deletes the user and ends all of their sessions. A non-zero version must
+// match the stored version. The last administrator cannot be deleted.
+func (s *userService) DeleteUser(ctx context.Context, id, version uint) error {
+	if err := authorizeUser(ctx, CanDelete, "delete", id); err != nil {
+		return err
+	}
 	logger := userlogger.GetUserLogger(id)
 	logger.Info("Deleting user", "userID", id)
 	// Add any business logic validation here and return pkg.NewInvalidInputError if needed
 
-	if err := s.repo.Delete(ctx, id); err != nil {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repoTx := s.repo.WithTx(tx)
+		user, err := repoTx.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 {
+			if err := checkVersion(user, version); err != nil {
+				return err
+			}
+		}
+		if err := ensureNotLastAdmin(ctx, s.roles.WithTx(tx), id); err != nil {
+			return err
+		}
 		// Propagate repository errors
+		return repoTx.Delete(ctx, id)
+	})
+	if err != nil {
 		return err
 	}
-	return nil
+
+	// A deleted user must not keep using tokens issued before the deletion
+	return s.auth.LogoutAll(ctx, id)
+}
+
+// DeleteOwnAccount deletes the user's own account after confirming their password
+func (s *userService) DeleteOwnAccount(ctx context.Context, id uint, password string) error {
+	if err := authorizeUser(ctx, CanDelete, "delete", id); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := confirmPassword(ctx, s.throttle, s.hasher, user, password); err != nil {
+		return err
+	}
+
+	return s.DeleteUser(ctx, id, 0)
+}
+
+// RestoreUser undoes the deletion of a user. The user's sessions stay revoked.
+func (s *userService) RestoreUser(ctx context.Context, id uint) (*model.User, error) {
+	if err := authorizeUser(ctx, CanRestore, "restore", id); err != nil {
+		return nil, err
+	}
+	logger := userlogger.GetUserLogger(id)
+
+	if err := s.repo.Restore(ctx, id); err != nil {
+		logger.Errorw("Failed to restore user", "userID", id, "error", err)
+		return nil, err
+	}
+
+	logger.Info("User restored", "userID", id)
+	return s.repo.GetByID(ctx, id)
+}
+
+// PurgeDeletedUsers permanently deletes users that were deleted longer ago than the retention
+// period. It is run by a background job, not on behalf of a principal.
+func (s *userService) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	if s.settings.DeletedUserRetention <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-s.settings.DeletedUserRetention)
+
+	var total int64
+	for {
+		purged, err := s.repo.PurgeDeletedBefore(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return total, err
+		}
+		for i := range purged {
+			removeFiles(ctx, s.blob, purged[i].AvatarKeys())
+		}
+		total += int64(len(purged))
+		if len(purged) < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.SystemLog.Infow("Purged deleted users", "count", total, "deleted_before", cutoff)
+	}
+	return total, nil
 }
 
 // RegisterUser creates a new user with hashed password
@@ -105,11 +310,6 @@ func (s *userService) RegisterUser(ctx context.Context, user *model.User) error
 		return pkg.NewValidationError("email", user.Email, "Email is required")
 	}
 
-	// Validate password strength
-	if len(user.Password) < 6 {
-		return pkg.NewValidationError("password", "***", "Password must be at least 6 characters long")
-	}
-
 	// Validate name
 	if user.Name == "" {
 		return pkg.NewValidationError("name", user.Name, "Name is required")
@@ -120,83 +320,163 @@ func (s *userService) RegisterUser(ctx context.Context, user *model.User) error
 		return pkg.NewValidationError("phone", user.Phone, "Phone number is required")
 	}
 
+	if err := s.prepareNewUser(user); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	// The account exists even if the email cannot be sent; the user can ask for a new link
+	if err := s.auth.SendVerificationEmail(ctx, user); err != nil {
+		logger.Errorw("Failed to send verification email after registration", "userID", user.ID, "error", err)
+	}
+	return nil
+}
+
+// prepareNewUser validates the password of a user about to be created against the policy,
+// replaces it with its hash and clears the fields only the dedicated flows may set
+func (s *userService) prepareNewUser(user *model.User) error {
+	// Validate password strength
+	if err := s.policy.Validate(user.Password, pkg.PasswordOwner{Name: user.Name, Email: user.Email}); err != nil {
+		return err
+	}
+
 	// Hash the password before storing
-	hashedPassword, err := pkg.HashPassword(user.Password)
+	hashedPassword, err := s.hasher.Hash(user.Password)
 	if err != nil {
 		return pkg.NewInternalServerError(err, "Failed to hash password")
 	}
 	user.Password = hashedPassword
-
-	if err := s.repo.Create(ctx, user); err != nil {
-		return err
-	}
+	// Ownership of the address is only proven through the verification link
+	user.EmailVerifiedAt = nil
+	// Two-factor authentication is only enabled through the enrolment flow
+	user.MFAEnabledAt = nil
+	user.TOTPSecret = ""
 	return nil
 }
 
-// LoginUser authenticates a user with email and password and returns token pair
-func (s *userService) LoginUser(ctx context.Context, email, password string) (*model.User, error) {
+// LoginUser authenticates a user with email and password and returns token pair.
+// Repeated failures lock the account and the client IP out for a while.
+func (s *userService) LoginUser(ctx context.Context, email, password, clientIP string) (*model.User, error) {
+	if err := s.throttle.Check(ctx, email, clientIP); err != nil {
+		return nil, err
+	}
+
 	user, err := s.GetUserByEmail(ctx, email)
 	if err != nil {
 		// Convert NotFoundError to UnauthorizedError to avoid revealing user existence
-		return nil, pkg.NewUnauthorizedError("Invalid email or password")
+		return nil, s.loginFailed(ctx, email, clientIP)
 	}
 
 	// Check password
-	if err := pkg.CheckPassword(user.Password, password); err != nil {
-		return nil, pkg.NewUnauthorizedError("Invalid email or password")
+	if err := s.hasher.Verify(user.Password, password); err != nil {
+		return nil, s.loginFailed(ctx, email, clientIP)
+	}
+
+	// The plain password is only available now, so upgrade outdated hashes while we have it
+	if s.hasher.NeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user, password)
+	}
+
+	// With two-factor authentication the login is only complete once the code is verified,
+	// so the counters must survive until then
+	if user.MFAEnabledAt == nil {
+		if err := s.throttle.RecordSuccess(ctx, email); err != nil {
+			logger.APILog.Errorw("Failed to reset login attempts", "userID", user.ID, "error", err)
+		}
+	}
+
+	if s.settings.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, pkg.NewForbiddenError("user", "login", "Email address has not been verified")
 	}
 
 	return user, nil
 }
 
-// GetUserByEmail retrieves a user by email
-func (s *userService) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
-	user, err := s.repo.GetByEmail(ctx, email)
+// UnlockUser lifts a login lockout on the user's account
+func (s *userService) UnlockUser(ctx context.Context, id uint) error {
+	if err := authorizeUser(ctx, CanUnlock, "unlock", id); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return user, nil
+	if err := s.throttle.Unlock(ctx, user.Email); err != nil {
+		return err
+	}
+
+	userlogger.GetUserLogger(id).Info("Login lockout lifted", "userID", id)
+	return nil
 }
 
-// RefreshToken validates a refresh token and returns the associated user
-func (s *userService) RefreshToken(ctx context.Context, refreshToken string) (*model.User, error) {
-	user, err := s.repo.GetByRefreshToken(ctx, refreshToken)
+// rehashPassword stores a new hash of password with the current algorithm and parameters.
+// Failures are only logged: the old hash still works and the next login tries again.
+func (s *userService) rehashPassword(ctx context.Context, user *model.User, password string) {
+	logger := userlogger.GetUserLogger(user.ID)
+
+	hashedPassword, err := s.hasher.Hash(password)
 	if err != nil {
-		// Convert NotFoundError to UnauthorizedError for security
-		return nil, pkg.NewUnauthorizedError("Invalid or expired refresh token")
+		logger.Errorw("Failed to rehash password", "userID", user.ID, "error", err)
+		return
+	}
+	user.Password = hashedPassword
+	if err := s.repo.Update(ctx, user); err != nil {
+		logger.Errorw("Failed to store rehashed password", "userID", user.ID, "error", err)
+		return
 	}
+	logger.Info("Password hash upgraded", "userID", user.ID)
+}
 
-	// Check if the refresh token has expired
-	if user.TokenExpiry != nil && time.Now().After(*user.TokenExpiry) {
-		return nil, pkg.NewUnauthorizedError("Refresh token has expired")
+// loginFailed records a failed login and returns the error for the client
+func (s *userService) loginFailed(ctx context.Context, email, clientIP string) error {
+	if err := s.throttle.RecordFailure(ctx, email, clientIP); err != nil {
+		return err
 	}
+	return pkg.NewUnauthorizedError("Invalid email or password")
+}
 
+// GetUserByEmail retrieves a user by email
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
-// UpdateRefreshToken updates a user's refresh token and expiry
-func (s *userService) UpdateRefreshToken(ctx context.Context, userID uint, refreshToken string, expiry time.Time) error {
-	logger := userlogger.GetUserLogger(userID)
-	logger.Info("Updating refresh token for user", "userID", userID)
-
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		repoTx := s.repo.WithTx(tx)
-
-		user, err := repoTx.GetByID(ctx, userID)
-		if err != nil {
-			logger.Errorw("User not found during refresh token update", "userID", userID, "error", err)
-			return err
-		}
+// checkVersion fails when an expected version is given and the user has moved past it
+func checkVersion(user *model.User, version uint) error {
+	if version != 0 && user.Version != version {
+		return pkg.NewPreconditionFailedError(pkg.VersionETag(user.Version), "User with ID %d has been changed since version %d", user.ID, version)
+	}
+	return nil
+}
 
-		user.RefreshToken = refreshToken
-		user.TokenExpiry = &expiry
+// userDocument is the JSON document user patches are applied to. Fields that are not
+// listed here cannot be patched.
+type userDocument struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
 
-		if err := repoTx.Update(ctx, user); err != nil {
-			logger.Errorw("Failed to update refresh token", "userID", userID, "error", err)
-			return err
-		}
+func newUserDocument(user *model.User) userDocument {
+	return userDocument{Name: user.Name, Email: user.Email, Phone: user.Phone}
+}
 
-		logger.Info("Refresh token updated successfully", "userID", userID)
-		return nil
-	})
+// validate checks the patched user before it is saved
+func (d userDocument) validate() error {
+	if strings.TrimSpace(d.Name) == "" {
+		return pkg.NewValidationError("name", d.Name, "Name is required")
+	}
+	if address, err := mail.ParseAddress(d.Email); err != nil || address.Address != d.Email {
+		return pkg.NewValidationError("email", d.Email, "Email must be a valid email address")
+	}
+	if strings.TrimSpace(d.Phone) == "" {
+		return pkg.NewValidationError("phone", d.Phone, "Phone number is required")
+	}
+	return nil
 }
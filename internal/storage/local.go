@@ -0,0 +1,102 @@
+// internal/storage/local.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"your_project/internal/pkg"
+)
+
+// LocalBlob stores files in a directory. The filesystem keeps no content type,
+// so it is derived from the extension of the key.
+type LocalBlob struct {
+	dir string
+}
+
+func NewLocalBlob(dir string) (*LocalBlob, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlob{dir: dir}, nil
+}
+
+func (b *LocalBlob) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(b.dir, filepath.FromSlash(cleaned)), nil
+}
+
+// Put writes to a temporary file first, so readers never see a partial file
+func (b *LocalBlob) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	target, err := b.path(key)
+	if err != nil {
+		return pkg.NewInvalidInputError("%v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return pkg.NewInternalServerError(err, "failed to create directory for %s", key)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return pkg.NewInternalServerError(err, "failed to store %s", key)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return pkg.NewInternalServerError(err, "failed to store %s", key)
+	}
+	if err := file.Close(); err != nil {
+		return pkg.NewInternalServerError(err, "failed to store %s", key)
+	}
+	if err := os.Rename(file.Name(), target); err != nil {
+		return pkg.NewInternalServerError(err, "failed to store %s", key)
+	}
+	return nil
+}
+
+func (b *LocalBlob) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	source, err := b.path(key)
+	if err != nil {
+		return nil, nil, pkg.NewInvalidInputError("%v", err)
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, pkg.NewFileNotFoundError(key, "File %s not found", key)
+		}
+		return nil, nil, pkg.NewInternalServerError(err, "failed to open %s", key)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, pkg.NewInternalServerError(err, "failed to open %s", key)
+	}
+
+	return file, &Object{
+		Key:         key,
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (b *LocalBlob) Delete(ctx context.Context, key string) error {
+	target, err := b.path(key)
+	if err != nil {
+		return pkg.NewInvalidInputError("%v", err)
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return pkg.NewInternalServerError(err, "failed to delete %s", key)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// internal/storage/s3.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"your_project/internal/pkg"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Blob stores files in a bucket of an S3-compatible service such as AWS S3 or MinIO
+type S3Blob struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Blob connects to the service and creates the bucket if it does not exist yet
+func NewS3Blob(ctx context.Context, cfg Config) (*S3Blob, error) {
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+		return nil, fmt.Errorf("the s3 storage driver needs S3_ENDPOINT and S3_BUCKET")
+	}
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure: cfg.S3UseSSL,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket %s: %w", cfg.S3Bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.S3Bucket, minio.MakeBucketOptions{Region: cfg.S3Region}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", cfg.S3Bucket, err)
+		}
+	}
+
+	return &S3Blob{client: client, bucket: cfg.S3Bucket}, nil
+}
+
+func (b *S3Blob) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return pkg.NewInvalidInputError("%v", err)
+	}
+	if _, err := b.client.PutObject(ctx, b.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType}); err != nil {
+		return pkg.NewServiceUnavailableError("storage", err, "failed to store %s", key)
+	}
+	return nil
+}
+
+func (b *S3Blob) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, nil, pkg.NewInvalidInputError("%v", err)
+	}
+
+	// GetObject only fails on the first read, so a missing key is detected with a stat first
+	info, err := b.client.StatObject(ctx, b.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return nil, nil, pkg.NewFileNotFoundError(key, "File %s not found", key)
+		}
+		return nil, nil, pkg.NewServiceUnavailableError("storage", err, "failed to read %s", key)
+	}
+	object, err := b.client.GetObject(ctx, b.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, pkg.NewServiceUnavailableError("storage", err, "failed to read %s", key)
+	}
+
+	return object, &Object{
+		Key:         key,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		ModTime:     info.LastModified,
+	}, nil
+}
+
+func (b *S3Blob) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return pkg.NewInvalidInputError("%v", err)
+	}
+	// Removing a missing object succeeds in S3
+	if err := b.client.RemoveObject(ctx, b.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return pkg.NewServiceUnavailableError("storage", err, "failed to delete %s", key)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// internal/storage/storage.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Object describes a stored file
+type Object struct {
+	Key         string
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Blob stores files under slash-separated keys such as "avatars/7/3f2a.png".
+// Get returns a pkg.FileNotFoundError for a missing key; Delete ignores one.
+type Blob interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Supported storage drivers
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// Config selects and configures the storage driver
+type Config struct {
+	Driver   string
+	LocalDir string // Root directory of the local driver
+
+	// S3-compatible object storage, e.g. AWS S3 or MinIO
+	S3Endpoint  string
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+	S3UseSSL    bool
+}
+
+// New creates the blob store for the configured driver
+func New(ctx context.Context, cfg Config) (Blob, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		return NewLocalBlob(cfg.LocalDir)
+	case DriverS3:
+		return NewS3Blob(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Driver)
+	}
+}
+
+// cleanKey rejects keys that are empty, absolute or escape the store
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean(key)
+	if key == "" || strings.HasPrefix(key, "/") || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"your_project/internal/pkg"
+)
+
+// testBlob runs the behaviour every Blob implementation must share
+func testBlob(t *testing.T, blob Blob) {
+	ctx := context.Background()
+	key := "avatars/7/test.png"
+	content := []byte("not really a png")
+
+	if err := blob.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	file, object, err := blob.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("reading %s: %v", key, err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("Get returned %q, want %q", data, content)
+	}
+	if object.Key != key || object.Size != int64(len(content)) || object.ContentType != "image/png" {
+		t.Errorf("Get returned object %+v", object)
+	}
+
+	// Putting the same key again replaces the file
+	replacement := []byte("replaced")
+	if err := blob.Put(ctx, key, bytes.NewReader(replacement), int64(len(replacement)), "image/png"); err != nil {
+		t.Fatalf("Put again: %v", err)
+	}
+	file, _, err = blob.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get after replace: %v", err)
+	}
+	data, _ = io.ReadAll(file)
+	file.Close()
+	if !bytes.Equal(data, replacement) {
+		t.Errorf("Get after replace returned %q, want %q", data, replacement)
+	}
+
+	if err := blob.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	var notFoundErr *pkg.FileNotFoundError
+	if _, _, err := blob.Get(ctx, key); !errors.As(err, &notFoundErr) {
+		t.Errorf("Get after Delete returned %v, want a FileNotFoundError", err)
+	}
+	if err := blob.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key returned %v, want nil", err)
+	}
+
+	var invalidErr *pkg.InvalidInputError
+	for _, bad := range []string{"", "/etc/passwd", "../outside", "avatars/../../outside"} {
+		if err := blob.Put(ctx, bad, bytes.NewReader(content), int64(len(content)), "image/png"); !errors.As(err, &invalidErr) {
+			t.Errorf("Put(%q) returned %v, want an InvalidInputError", bad, err)
+		}
+		if _, _, err := blob.Get(ctx, bad); !errors.As(err, &invalidErr) {
+			t.Errorf("Get(%q) returned %v, want an InvalidInputError", bad, err)
+		}
+	}
+}
+
+func TestLocalBlob(t *testing.T) {
+	blob, err := NewLocalBlob(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlob(t, blob)
+}
+
+// TestS3Blob runs against a real S3-compatible service, e.g.
+// docker run -p 9000:9000 minio/minio server /data, then MINIO_ENDPOINT=localhost:9000
+func TestS3Blob(t *testing.T) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_ENDPOINT is not set")
+	}
+	cfg := Config{
+		Driver:      DriverS3,
+		S3Endpoint:  endpoint,
+		S3Bucket:    envOr("MINIO_BUCKET", "storage-test"),
+		S3AccessKey: envOr("MINIO_ACCESS_KEY", "minioadmin"),
+		S3SecretKey: envOr("MINIO_SECRET_KEY", "minioadmin"),
+		S3UseSSL:    os.Getenv("MINIO_USE_SSL") == "true",
+	}
+
+	blob, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlob(t, blob)
+}
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "avatars/7/a.png", want: "avatars/7/a.png"},
+		{key: "avatars//7/./a.png", want: "avatars/7/a.png"},
+		{key: "avatars/../a.png", want: "a.png"},
+		{key: "", wantErr: true},
+		{key: ".", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: "../a.png", wantErr: true},
+		{key: "/a.png", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := cleanKey(tt.key)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("cleanKey(%q) = %q, %v; want %q, error %t", tt.key, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -3,18 +3,45 @@ package migrations
 
 import (
 	"your_project/internal/model"
+	"your_project/internal/pkg"
 
 	"gorm.io/gorm"
 )
 
 // AutoMigrate runs GORM AutoMigrate for all models
 func AutoMigrate(db *gorm.DB) error {
+	// Custom migrations that must run before the models are migrated
+	if err := InvalidatePlaintextRefreshTokens(db); err != nil {
+		return err
+	}
+	if err := DropUserEmailUniqueIndex(db); err != nil {
+		return err
+	}
+	if err := DropCaseSensitiveEmailIndex(db); err != nil {
+		return err
+	}
+
 	// Add all your models here for auto-migration
-	err := db.AutoMigrate(&model.User{})
+	err := db.AutoMigrate(
+		&model.User{},
+		&model.RefreshToken{},
+		&model.Session{},
+		&model.PasswordResetToken{},
+		&model.EmailVerificationToken{},
+		&model.EmailChangeRequest{},
+		&model.RecoveryCode{},
+		&model.Role{},
+		&model.Permission{},
+		&model.AuditLog{},
+	)
 	if err != nil {
 		return err
 	}
 
+	if err := SeedRolesAndPermissions(db); err != nil {
+		return err
+	}
+
 	// Add other models here as needed:
 	// err = db.AutoMigrate(&model.AnotherModel{})
 	// if err != nil {
@@ -24,6 +51,117 @@ func AutoMigrate(db *gorm.DB) error {
 	return nil
 }
 
+// InvalidatePlaintextRefreshTokens removes refresh tokens that were stored in plaintext,
+// either in the legacy users.refresh_token column or in refresh_tokens.token.
+// They cannot be converted to hashes without trusting them, so the affected sessions
+// are revoked and users have to log in again. The migration is idempotent.
+func InvalidatePlaintextRefreshTokens(db *gorm.DB) error {
+	const name = "invalidate_plaintext_refresh_tokens"
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+
+		if migrator.HasColumn("users", "refresh_token") {
+			if err := tx.Exec("ALTER TABLE users DROP COLUMN refresh_token").Error; err != nil {
+				return pkg.NewMigrationError(name, err, "failed to drop users.refresh_token")
+			}
+		}
+		if migrator.HasColumn("users", "token_expiry") {
+			if err := tx.Exec("ALTER TABLE users DROP COLUMN token_expiry").Error; err != nil {
+				return pkg.NewMigrationError(name, err, "failed to drop users.token_expiry")
+			}
+		}
+
+		if migrator.HasColumn("refresh_tokens", "token") {
+			if err := tx.Exec("DELETE FROM refresh_tokens").Error; err != nil {
+				return pkg.NewMigrationError(name, err, "failed to delete plaintext refresh tokens")
+			}
+			if err := tx.Exec("ALTER TABLE refresh_tokens DROP COLUMN token").Error; err != nil {
+				return pkg.NewMigrationError(name, err, "failed to drop refresh_tokens.token")
+			}
+			// Sessions without a usable refresh token can no longer be refreshed
+			if migrator.HasTable("sessions") {
+				if err := tx.Exec("UPDATE sessions SET revoked_at = NOW() WHERE revoked_at IS NULL").Error; err != nil {
+					return pkg.NewMigrationError(name, err, "failed to revoke sessions")
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// DropUserEmailUniqueIndex drops the unique index on users.email that also covered deleted
+// users. AutoMigrate replaces it with idx_users_email_lower_active, which only covers active users,
+// so a deleted user's address can be registered again. The migration is idempotent.
+func DropUserEmailUniqueIndex(db *gorm.DB) error {
+	const name = "drop_user_email_unique_index"
+
+	if !db.Migrator().HasTable("users") {
+		return nil
+	}
+	if err := db.Exec("DROP INDEX IF EXISTS idx_users_email").Error; err != nil {
+		return pkg.NewMigrationError(name, err, "failed to drop idx_users_email")
+	}
+	return nil
+}
+
+// DropCaseSensitiveEmailIndex drops idx_users_email_active, which let two active users have
+// the same address in different case. AutoMigrate replaces it with idx_users_email_lower_active
+// on lower(email); that fails until such duplicates are resolved. The migration is idempotent.
+func DropCaseSensitiveEmailIndex(db *gorm.DB) error {
+	const name = "drop_case_sensitive_email_index"
+
+	if !db.Migrator().HasTable("users") {
+		return nil
+	}
+	if err := db.Exec("DROP INDEX IF EXISTS idx_users_email_active").Error; err != nil {
+		return pkg.NewMigrationError(name, err, "failed to drop idx_users_email_active")
+	}
+	return nil
+}
+
+// permissionCatalogue lists every permission the API checks
+var permissionCatalogue = []model.Permission{
+	{Name: model.PermissionUsersRead, Description: "View any user"},
+	{Name: model.PermissionUsersReadDeleted, Description: "Include deleted users in listings"},
+	{Name: model.PermissionUsersImport, Description: "Import users in bulk"},
+	{Name: model.PermissionUsersUpdate, Description: "Update any user"},
+	{Name: model.PermissionUsersDelete, Description: "Delete any user"},
+	{Name: model.PermissionUsersRestore, Description: "Restore deleted users"},
+	{Name: model.PermissionUsersUnlock, Description: "Lift login lockouts"},
+	{Name: model.PermissionUsersErase, Description: "Erase the personal data of any user"},
+	{Name: model.PermissionRolesRead, Description: "View roles and role assignments"},
+	{Name: model.PermissionRolesAssign, Description: "Assign and remove roles"},
+}
+
+// SeedRolesAndPermissions creates the permission catalogue and the admin role,
+// which holds every permission. The migration is idempotent.
+func SeedRolesAndPermissions(db *gorm.DB) error {
+	const name = "seed_roles_and_permissions"
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		permissions := make([]model.Permission, 0, len(permissionCatalogue))
+		for _, p := range permissionCatalogue {
+			permission := model.Permission{Name: p.Name}
+			if err := tx.Where(model.Permission{Name: p.Name}).Attrs(model.Permission{Description: p.Description}).FirstOrCreate(&permission).Error; err != nil {
+				return pkg.NewMigrationError(name, err, "failed to seed permission %s", p.Name)
+			}
+			permissions = append(permissions, permission)
+		}
+
+		admin := model.Role{Name: model.RoleAdmin}
+		if err := tx.Where(model.Role{Name: model.RoleAdmin}).Attrs(model.Role{Description: "Full access to users and roles"}).FirstOrCreate(&admin).Error; err != nil {
+			return pkg.NewMigrationError(name, err, "failed to seed role %s", model.RoleAdmin)
+		}
+		if err := tx.Model(&admin).Association("Permissions").Replace(permissions); err != nil {
+			return pkg.NewMigrationError(name, err, "failed to grant permissions to role %s", model.RoleAdmin)
+		}
+
+		return nil
+	})
+}
+
 // You can define more complex migrations here if needed,
 // for example, using raw SQL or GORM's migration features
 // func CustomMigration1(db *gorm.DB) error {